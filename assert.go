@@ -0,0 +1,286 @@
+package yamlfix
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AssertOption はテーブルの内容と期待値の比較方法を変更するオプション
+type AssertOption func(*assertOptions)
+
+// assertOptions はテーブルの比較方法の設定
+type assertOptions struct {
+	ignoreColumns map[string]bool
+	subset        bool
+	ignoreOrder   bool
+	tables        []string
+	executor      Executor
+}
+
+// IgnoreColumns は期待値に記述されていても比較しないカラムを指定する（作成日時など）
+func IgnoreColumns(columns ...string) AssertOption {
+	return func(o *assertOptions) {
+		for _, col := range columns {
+			o.ignoreColumns[col] = true
+		}
+	}
+}
+
+// Subset は期待値のレコードがすべて含まれていれば、テーブルに他の行があっても一致とみなす（順序は問わない）
+func Subset() AssertOption {
+	return func(o *assertOptions) {
+		o.subset = true
+	}
+}
+
+// IgnoreOrder は行の順序を問わずに比較する
+// 指定しない場合、主キーの順に並べたテーブルの行と期待値の記述順が異なると一致しない
+func IgnoreOrder() AssertOption {
+	return func(o *assertOptions) {
+		o.ignoreOrder = true
+	}
+}
+
+// ReadFrom はテーブルを読み取るトランザクションなどを指定する
+// Run / RunWithSetup のサブテストでは、サブテストに渡されたトランザクションを指定する
+func ReadFrom(executor Executor) AssertOption {
+	return func(o *assertOptions) {
+		o.executor = executor
+	}
+}
+
+// newAssertOptions はオプションを適用した比較方法の設定を作成する
+func newAssertOptions(opts []AssertOption) *assertOptions {
+	o := &assertOptions{ignoreColumns: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// AssertTable はテーブルの内容が期待値のYAMLと一致することを検証する
+// 期待値はフィクスチャと同じ形式で、単一テーブル形式のほか table を含む複数テーブル形式も指定できる
+// 比較するのは期待値に記述したカラムのみで、テーブルはフィクスチャのトランザクション（ReadFrom で指定可能）から読み取る
+func (tf *TestFixture) AssertTable(t testing.TB, table, expectedYAML string, opts ...AssertOption) {
+	t.Helper()
+	tf.assertTable(tf.ctx, t, table, expectedYAML, opts)
+}
+
+// AssertDatabase はファイル・ディレクトリ・グロブで指定した期待値のYAMLに含まれる各テーブルの内容を検証する
+func (tf *TestFixture) AssertDatabase(t testing.TB, path string, opts ...AssertOption) {
+	t.Helper()
+	tf.assertDatabase(tf.ctx, t, path, opts)
+}
+
+// AssertTable はテーブルの内容が期待値のYAMLと一致することをセッションのトランザクションから読み取って検証する
+func (s *Session) AssertTable(t testing.TB, table, expectedYAML string, opts ...AssertOption) {
+	t.Helper()
+	s.fixture.assertTable(t.Context(), t, table, expectedYAML, opts)
+}
+
+// AssertDatabase は期待値のYAMLに含まれる各テーブルの内容をセッションのトランザクションから読み取って検証する
+func (s *Session) AssertDatabase(t testing.TB, path string, opts ...AssertOption) {
+	t.Helper()
+	s.fixture.assertDatabase(t.Context(), t, path, opts)
+}
+
+// assertTable は期待値のYAMLを解析してテーブルの内容と比較する
+func (f *Fixture) assertTable(ctx context.Context, t testing.TB, table, expectedYAML string, opts []AssertOption) {
+	t.Helper()
+
+	expected, err := f.decodeExpectedTable(table, []byte(expectedYAML))
+	if err != nil {
+		t.Fatalf("failed to parse expected data for table %s: %v", table, err)
+	}
+
+	f.assertRecords(ctx, t, table, expected, newAssertOptions(opts))
+}
+
+// assertDatabase は期待値のYAMLを読み込んで各テーブルの内容と比較する
+func (f *Fixture) assertDatabase(ctx context.Context, t testing.TB, path string, opts []AssertOption) {
+	t.Helper()
+
+	// 期待値は読み込み済みのフィクスチャとは別に読み込む
+	expected := f.newChild()
+	if err := expected.loadPatterns(expected.fsys, []string{path}); err != nil {
+		t.Fatalf("failed to load expected data: %v", err)
+	}
+
+	options := newAssertOptions(opts)
+	for _, table := range expected.tableOrder {
+		if len(options.tables) > 0 && !containsString(options.tables, table) {
+			continue
+		}
+		if err := checkExpected(expected.fixtures[table]); err != nil {
+			t.Fatalf("failed to load expected data for table %s: %v", table, err)
+		}
+		f.assertRecords(ctx, t, table, expected.fixtures[table], options)
+	}
+}
+
+// assertRecords はテーブルの内容と期待値のレコードを比較し、一致しない場合はテストを失敗させて false を返す
+func (f *Fixture) assertRecords(ctx context.Context, t testing.TB, table string, expected []*record, options *assertOptions) bool {
+	t.Helper()
+
+	actual, err := f.queryTable(ctx, f.readExecutor(options), table)
+	if err != nil {
+		t.Fatalf("failed to read table %s: %v", table, err)
+	}
+
+	diff, err := f.compareTable(table, expected, actual, options)
+	if err != nil {
+		t.Fatalf("failed to compare table %s: %v", table, err)
+	}
+	if diff != "" {
+		t.Error(diff)
+		return false
+	}
+	return true
+}
+
+// readExecutor はテーブルを読み取る Executor を返す（ReadFrom の指定がなければフィクスチャのトランザクション）
+func (f *Fixture) readExecutor(options *assertOptions) Executor {
+	if options.executor != nil {
+		return options.executor
+	}
+	return f.getExecutor()
+}
+
+// decodeExpectedTable は期待値のYAMLを table のレコードに変換する
+// 複数テーブル形式の場合は table のエントリのみを使う（同じテーブルは1度しか記述できない）
+func (f *Fixture) decodeExpectedTable(table string, data []byte) ([]*record, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, &LoadError{Line: yamlErrorLine(err), Err: fmt.Errorf("failed to parse YAML: %w", err)}
+	}
+	root := documentRoot(&doc)
+
+	var expected *tableData
+	if f.isMultiTableFormat(root) {
+		decoded, err := decodeTables(root, "")
+		if err != nil {
+			return nil, err
+		}
+		for i := range decoded {
+			if decoded[i].name == table {
+				expected = &decoded[i]
+				break
+			}
+		}
+		if expected == nil {
+			return nil, fmt.Errorf("expected data does not contain table %s", table)
+		}
+	} else {
+		records, defaults, err := decodeRecords(root, "")
+		if err != nil {
+			return nil, err
+		}
+		expected = &tableData{name: table, records: records, defaults: defaults}
+	}
+
+	if err := f.applyDefaults([]tableData{*expected}); err != nil {
+		return nil, err
+	}
+	if err := checkExpected(expected.records); err != nil {
+		return nil, err
+	}
+	return expected.records, nil
+}
+
+// checkExpected は期待値に使えない参照やSQL式が含まれていないことを確認する
+func checkExpected(records []*record) error {
+	for _, rec := range records {
+		for _, col := range rec.columns {
+			switch rec.values[col].(type) {
+			case reference:
+				return loadErrorf(rec.source, rec.line, col, "references cannot be used in expected data")
+			case rawSQL:
+				return loadErrorf(rec.source, rec.line, col, "%s values cannot be used in expected data", rawSQLTag)
+			}
+		}
+	}
+	return nil
+}
+
+// tableRows はデータベースから読み取ったテーブルの内容
+type tableRows struct {
+	columns []string
+	rows    []map[string]interface{}
+}
+
+// queryTable はテーブルのすべての行を読み取る
+func (f *Fixture) queryTable(ctx context.Context, executor Executor, table string) (*tableRows, error) {
+	rows, err := executor.QueryContext(ctx, "SELECT * FROM "+f.dialect.QuoteIdentifier(table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &tableRows{columns: columns}
+	for rows.Next() {
+		dest := make([]interface{}, len(columns))
+		for i := range dest {
+			dest[i] = new(interface{})
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			value := *(dest[i].(*interface{}))
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			row[col] = value
+		}
+		result.rows = append(result.rows, row)
+	}
+	return result, rows.Err()
+}
+
+// compareTable は期待値のレコードとテーブルの内容を比較し、差分を返す（一致する場合は空文字）
+func (f *Fixture) compareTable(table string, expected []*record, actual *tableRows, options *assertOptions) (string, error) {
+	known := make(map[string]bool, len(actual.columns))
+	for _, col := range actual.columns {
+		known[col] = true
+	}
+
+	// 期待値の値は挿入時と同じ変換を行ってから比較する
+	var missingColumns []string
+	rows := make([]expectedRow, len(expected))
+	for i, rec := range expected {
+		row := expectedRow{rec: rec, values: make(map[string]interface{}, len(rec.columns))}
+		for _, col := range rec.columns {
+			if options.ignoreColumns[col] {
+				continue
+			}
+			if !known[col] {
+				if !containsString(missingColumns, col) {
+					missingColumns = append(missingColumns, col)
+				}
+				continue
+			}
+
+			value, err := f.convertValue(table, col, rec.values[col])
+			if err != nil {
+				return "", &LoadError{SourceFile: rec.source, Line: rec.line, Column: col, Err: err}
+			}
+			row.columns = append(row.columns, col)
+			row.values[col] = value
+		}
+		rows[i] = row
+	}
+
+	diff := diffRows(table, f.primaryKey(table), rows, actual, options, f.timeLocation)
+	diff.missingColumns = missingColumns
+	return diff.String(), nil
+}
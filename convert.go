@@ -0,0 +1,131 @@
+package yamlfix
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValueConverter はバインド前にカラムの値を変換する関数（変換しない値はそのまま返す）
+type ValueConverter func(table, column string, value interface{}) (interface{}, error)
+
+const (
+	// binaryTag はbase64で記述したバイト列のYAMLタグ
+	binaryTag = "!!binary"
+	// timestampTag は日時として解釈されたスカラーのYAMLタグ
+	timestampTag = "!!timestamp"
+	// decimalTag は精度を保つため文字列のまま挿入する10進数のYAMLタグ
+	decimalTag = "!decimal"
+)
+
+// timeLayouts は Config.TimeLocation を指定した場合に日時として解釈する文字列の形式
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// decodeValue はYAMLのタグに応じてカラムの値を変換する
+func decodeValue(node *yaml.Node) (interface{}, error) {
+	if node.Kind == yaml.ScalarNode {
+		switch node.Tag {
+		case binaryTag:
+			data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(node.Value), ""))
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value: %w", binaryTag, err)
+			}
+			return data, nil
+		case timestampTag:
+			// 引用符の有無で型が変わらないよう、日時は記述した文字列のまま扱う
+			return node.Value, nil
+		case decimalTag:
+			if _, ok := new(big.Float).SetString(node.Value); !ok {
+				return nil, fmt.Errorf("invalid %s value %q", decimalTag, node.Value)
+			}
+			return node.Value, nil
+		}
+	}
+
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// convertValue は ValueConverter、組み込みの変換の順にカラムの値を変換する
+// 方言による変換はバインド時に行う
+func (f *Fixture) convertValue(tableName, column string, value interface{}) (interface{}, error) {
+	if _, ok := value.(rawSQL); ok {
+		return value, nil
+	}
+
+	if f.valueConverter != nil {
+		converted, err := f.valueConverter(tableName, column, value)
+		if err != nil {
+			return nil, err
+		}
+		value = converted
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+		// ネストしたマッピングやリストはJSONカラム向けに文字列へ変換する
+		data, err := json.Marshal(jsonValue(v))
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		return string(data), nil
+	case string:
+		if f.timeLocation != nil {
+			if t, ok := parseTime(v, f.timeLocation); ok {
+				return t, nil
+			}
+		}
+	}
+
+	return value, nil
+}
+
+// jsonValue はJSONに変換できるよう文字列以外のキーを持つマッピングを再帰的に変換する
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			m[fmt.Sprint(key)] = jsonValue(elem)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			m[key] = jsonValue(elem)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, elem := range v {
+			list[i] = jsonValue(elem)
+		}
+		return list
+	default:
+		return value
+	}
+}
+
+// parseTime は文字列を日時として解釈する（タイムゾーンの記述がない場合は loc とみなす）
+func parseTime(s string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,128 @@
+package yamlfix
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultsKey はテーブルの各レコードに適用するデフォルト値を記述するキー
+const defaultsKey = "_defaults"
+
+// isDefaultsNode は _defaults のみを持つマッピングノードかどうかを判定する
+func isDefaultsNode(node *yaml.Node) bool {
+	return node.Kind == yaml.MappingNode && len(node.Content) == 2 && node.Content[0].Value == defaultsKey
+}
+
+// decodeDefaults は _defaults のマッピングノードをデフォルト値のレコードに変換する
+func decodeDefaults(node *yaml.Node, filename string) (*record, error) {
+	defaults, err := decodeRecord(node, filename)
+	if err != nil {
+		return nil, err
+	}
+	if defaults.label != "" || defaults.deleted {
+		return nil, loadErrorf(filename, node.Line, "", "%s cannot contain %s or %s", defaultsKey, labelKey, deleteKey)
+	}
+	return defaults, nil
+}
+
+// applyDefaults は各テーブルのデフォルト値をレコードにマージする（レコードの値が優先される）
+// テンプレートが有効な場合、[[ ]] を含むデフォルト値はレコードごとに評価する
+func (f *Fixture) applyDefaults(tables []tableData) error {
+	now := time.Now()
+
+	for _, table := range tables {
+		defaults := table.defaults
+		if defaults == nil {
+			continue
+		}
+
+		templates, err := f.parseDefaultTemplates(defaults, now)
+		if err != nil {
+			return err
+		}
+
+		for _, rec := range table.records {
+			var filled []string
+			for _, col := range defaults.columns {
+				if _, ok := rec.values[col]; ok {
+					continue
+				}
+				rec.columns = append(rec.columns, col)
+				rec.values[col] = defaults.values[col]
+				filled = append(filled, col)
+			}
+
+			// テンプレートは静的なデフォルト値をマージした後のレコードを参照して評価する
+			for _, col := range filled {
+				tmpl, ok := templates[col]
+				if !ok {
+					continue
+				}
+
+				value, err := evaluateDefault(tmpl, rec)
+				if err != nil {
+					return loadErrorf(rec.source, rec.line, col, "failed to evaluate default: %w", err)
+				}
+				rec.values[col] = value
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseDefaultTemplates は [[ ]] を含むデフォルト値をテンプレートとして解析する
+func (f *Fixture) parseDefaultTemplates(defaults *record, now time.Time) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template)
+	if !f.template {
+		return templates, nil
+	}
+
+	for _, col := range defaults.columns {
+		text, ok := defaults.values[col].(string)
+		if !ok || !strings.Contains(text, "[[") {
+			continue
+		}
+
+		tmpl, err := template.New(col).
+			Delims("[[", "]]").
+			Option("missingkey=error").
+			Funcs(templateFuncs(now)).
+			Funcs(f.funcMap).
+			Parse(text)
+		if err != nil {
+			return nil, loadErrorf(defaults.source, defaults.line, col, "failed to parse default template: %w", err)
+		}
+		templates[col] = tmpl
+	}
+
+	return templates, nil
+}
+
+// evaluateDefault はレコードのカラム値をデータとしてテンプレートを評価し、結果をYAMLのスカラーとして解釈する
+func evaluateDefault(tmpl *template.Template, rec *record) (interface{}, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, rec.values); err != nil {
+		return nil, err
+	}
+
+	// 数値や真偽値はその型で扱い、スカラーとして解釈できない場合は文字列のまま使う
+	var node yaml.Node
+	if err := yaml.Unmarshal(buf.Bytes(), &node); err != nil {
+		return buf.String(), nil
+	}
+	root := documentRoot(&node)
+	if root == nil || root.Kind != yaml.ScalarNode {
+		return buf.String(), nil
+	}
+
+	value, err := decodeValue(root)
+	if err != nil {
+		return buf.String(), nil
+	}
+	return value, nil
+}
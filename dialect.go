@@ -0,0 +1,200 @@
+package yamlfix
+
+import (
+	"database/sql"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect はデータベースごとのSQL方言の差異を吸収するインターフェース
+type Dialect interface {
+	// Name は方言名を返す
+	Name() string
+	// Placeholder は n 番目（1始まり）のバインドパラメータのプレースホルダーを返す
+	Placeholder(n int) string
+	// QuoteIdentifier はテーブル名やカラム名をクォートする（スキーマ修飾名は各要素をクォートする）
+	QuoteIdentifier(name string) string
+	// ConvertValue はバインド前にデータベースが扱える形式へ値を変換する（真偽値や日時など）
+	ConvertValue(value interface{}) interface{}
+}
+
+// MySQLDialect はMySQL/MariaDB用の方言
+type MySQLDialect struct{}
+
+// Name は方言名を返す
+func (MySQLDialect) Name() string { return "mysql" }
+
+// Placeholder は ? を返す
+func (MySQLDialect) Placeholder(int) string { return "?" }
+
+// QuoteIdentifier はバッククォートで識別子をクォートする
+func (MySQLDialect) QuoteIdentifier(name string) string {
+	return quoteIdentifierParts(name, "`", "`")
+}
+
+// ConvertValue は真偽値をTINYINT向けの 1/0 に変換する（日時はドライバが接続の loc に合わせて変換する）
+func (MySQLDialect) ConvertValue(value interface{}) interface{} {
+	return boolToInt(value)
+}
+
+// PostgreSQLDialect はPostgreSQL用の方言（lib/pq, pgx）
+type PostgreSQLDialect struct{}
+
+// Name は方言名を返す
+func (PostgreSQLDialect) Name() string { return "postgres" }
+
+// Placeholder は $1, $2, ... を返す
+func (PostgreSQLDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+// QuoteIdentifier はダブルクォートで識別子をクォートする
+func (PostgreSQLDialect) QuoteIdentifier(name string) string {
+	return quoteIdentifierParts(name, `"`, `"`)
+}
+
+// ConvertValue は値をそのまま返す（ドライバがboolean/timestampを扱える）
+func (PostgreSQLDialect) ConvertValue(value interface{}) interface{} { return value }
+
+// SQLiteDialect はSQLite用の方言
+type SQLiteDialect struct{}
+
+// Name は方言名を返す
+func (SQLiteDialect) Name() string { return "sqlite" }
+
+// Placeholder は ? を返す
+func (SQLiteDialect) Placeholder(int) string { return "?" }
+
+// QuoteIdentifier はダブルクォートで識別子をクォートする
+func (SQLiteDialect) QuoteIdentifier(name string) string {
+	return quoteIdentifierParts(name, `"`, `"`)
+}
+
+// ConvertValue は真偽値を 1/0 に、日時をオフセット付きの文字列に変換する（SQLiteにはboolean型や日時型がない）
+// オフセットを残すため読み戻しても時刻がずれず、日付関数はUTCに換算して扱う
+func (SQLiteDialect) ConvertValue(value interface{}) interface{} {
+	if t, ok := value.(time.Time); ok {
+		return t.Format(sqliteTimeLayout)
+	}
+	return boolToInt(value)
+}
+
+// sqliteTimeLayout はSQLiteの日付関数と go-sqlite3 が解釈できるオフセット付きの日時の形式
+const sqliteTimeLayout = "2006-01-02 15:04:05.999999999Z07:00"
+
+// SQLServerDialect はSQL Server用の方言
+type SQLServerDialect struct{}
+
+// Name は方言名を返す
+func (SQLServerDialect) Name() string { return "sqlserver" }
+
+// Placeholder は @p1, @p2, ... を返す
+func (SQLServerDialect) Placeholder(n int) string { return "@p" + strconv.Itoa(n) }
+
+// QuoteIdentifier は角括弧で識別子をクォートする
+func (SQLServerDialect) QuoteIdentifier(name string) string {
+	return quoteIdentifierParts(name, "[", "]")
+}
+
+// ConvertValue は真偽値をBIT向けの 1/0 に、日時をUTCに変換する
+// datetime / datetime2 カラムはオフセットを捨てて時刻だけを保存するため、タイムゾーンによって値がずれないようにする
+func (SQLServerDialect) ConvertValue(value interface{}) interface{} {
+	if t, ok := value.(time.Time); ok {
+		return t.UTC()
+	}
+	return boolToInt(value)
+}
+
+// OracleDialect はOracle用の方言（godror, go-ora）
+type OracleDialect struct{}
+
+// Name は方言名を返す
+func (OracleDialect) Name() string { return "oracle" }
+
+// Placeholder は :1, :2, ... を返す
+func (OracleDialect) Placeholder(n int) string { return ":" + strconv.Itoa(n) }
+
+// QuoteIdentifier はダブルクォートで識別子をクォートする
+func (OracleDialect) QuoteIdentifier(name string) string {
+	return quoteIdentifierParts(name, `"`, `"`)
+}
+
+// ConvertValue は真偽値を NUMBER(1) 向けの 1/0 に変換する
+func (OracleDialect) ConvertValue(value interface{}) interface{} {
+	return boolToInt(value)
+}
+
+// genericDialect は方言を判定できない場合に使う従来互換の方言
+type genericDialect struct{}
+
+func (genericDialect) Name() string                               { return "generic" }
+func (genericDialect) Placeholder(int) string                     { return "?" }
+func (genericDialect) QuoteIdentifier(name string) string         { return name }
+func (genericDialect) ConvertValue(value interface{}) interface{} { return value }
+
+// driverDialects はドライバのパッケージパスと方言の対応表
+var driverDialects = []struct {
+	pkgPath string
+	dialect Dialect
+}{
+	{"github.com/go-sql-driver/mysql", MySQLDialect{}},
+	{"github.com/lib/pq", PostgreSQLDialect{}},
+	{"github.com/jackc/pgx", PostgreSQLDialect{}},
+	{"github.com/mattn/go-sqlite3", SQLiteDialect{}},
+	{"modernc.org/sqlite", SQLiteDialect{}},
+	{"github.com/microsoft/go-mssqldb", SQLServerDialect{}},
+	{"github.com/denisenkom/go-mssqldb", SQLServerDialect{}},
+	{"github.com/godror/godror", OracleDialect{}},
+	{"github.com/sijms/go-ora", OracleDialect{}},
+}
+
+// DetectDialect は *sql.DB のドライバ型から方言を推測する
+// 判定できない場合は ? プレースホルダーを使う汎用方言を返す
+func DetectDialect(db *sql.DB) Dialect {
+	if db == nil {
+		return genericDialect{}
+	}
+
+	typ := reflect.TypeOf(db.Driver())
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	pkgPath := typ.PkgPath()
+	for _, d := range driverDialects {
+		if pkgPath == d.pkgPath || strings.HasPrefix(pkgPath, d.pkgPath+"/") {
+			return d.dialect
+		}
+	}
+
+	return genericDialect{}
+}
+
+// quoteIdentifierParts はドット区切りの各要素をクォートする
+func quoteIdentifierParts(name, open, close string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = open + strings.ReplaceAll(part, close, close+close) + close
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteColumns はカラム名をクォートしてカンマ区切りで連結する
+func quoteColumns(d Dialect, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = d.QuoteIdentifier(col)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// boolToInt は真偽値を 1/0 に変換し、それ以外の値はそのまま返す
+func boolToInt(value interface{}) interface{} {
+	if b, ok := value.(bool); ok {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	return value
+}
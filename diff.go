@@ -0,0 +1,387 @@
+package yamlfix
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// expectedRow は比較用に値を変換した期待値の行
+type expectedRow struct {
+	rec     *record  // 期待値の記述位置（DiffTables に渡された行の場合は nil）
+	columns []string // 比較するカラム
+	values  map[string]interface{}
+}
+
+// changedRow は期待値と対応付けたテーブルの行のうち、値が異なるもの
+type changedRow struct {
+	label    string
+	expected expectedRow
+	actual   map[string]interface{}
+	columns  []string // 値が異なるカラム
+}
+
+// labeledRow は差分の表示用のラベルを付けた行
+type labeledRow struct {
+	label   string
+	columns []string
+	values  map[string]interface{}
+	rec     *record
+}
+
+// tableDiff は期待値とテーブルの内容の差分
+type tableDiff struct {
+	table          string
+	missingColumns []string // テーブルに存在しない期待値のカラム
+	changed        []changedRow
+	missing        []labeledRow // 期待値にあってテーブルにない行
+	unexpected     []labeledRow // テーブルにあって期待値にない行
+	order          string       // 行の順序の違い
+}
+
+// DiffTables は期待値と実際の行を主キーで対応付け、欠けた行・余分な行・値の異なる行の差分を文字列で返す
+// 一致する場合は空文字を返す。比較するのは期待値の各行に含まれるカラムで、行の順序は問わない
+// 主キーを含まない期待値の行は、値がすべて一致する行と対応付ける（primaryKey が空の場合は id）
+func DiffTables(table string, primaryKey []string, expected, actual []map[string]interface{}) string {
+	if len(primaryKey) == 0 {
+		primaryKey = defaultPrimaryKey
+	}
+
+	actualRows := &tableRows{
+		columns: mapColumns(primaryKey, actual),
+		rows:    append([]map[string]interface{}(nil), actual...),
+	}
+
+	rows := make([]expectedRow, len(expected))
+	for i, values := range expected {
+		rows[i] = expectedRow{columns: mapColumns(primaryKey, expected[i:i+1]), values: values}
+	}
+
+	return diffRows(table, primaryKey, rows, actualRows, &assertOptions{ignoreOrder: true}, nil).String()
+}
+
+// mapColumns は行のカラム名を主キー、残りのカラムの名前順に並べて返す
+func mapColumns(primaryKey []string, rows []map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var rest []string
+	for _, row := range rows {
+		for col := range row {
+			if !seen[col] && !containsString(primaryKey, col) {
+				rest = append(rest, col)
+			}
+			seen[col] = true
+		}
+	}
+	sort.Strings(rest)
+
+	var columns []string
+	for _, col := range primaryKey {
+		if seen[col] {
+			columns = append(columns, col)
+		}
+	}
+	return append(columns, rest...)
+}
+
+// diffRows は期待値の行とテーブルの行を対応付けて差分を求める
+// テーブルに主キーのカラムがあれば主キーで、なければ記述順（順序を問わない場合は値がすべて一致する行）で対応付ける
+func diffRows(table string, primaryKey []string, expected []expectedRow, actual *tableRows, options *assertOptions, loc *time.Location) *tableDiff {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	d := &tableDiff{table: table}
+	keyed := hasColumns(actual.columns, primaryKey)
+	ordered := !options.subset && !options.ignoreOrder
+	if keyed {
+		sortRows(primaryKey, actual.rows)
+	}
+
+	matched := make([]bool, len(actual.rows))
+	var matchedOrder []int // 対応付けたテーブルの行の位置（期待値の記述順）
+	var matchedLabels []string
+	for i, row := range expected {
+		label := fmt.Sprintf("row %d", i+1)
+		if keyed && hasColumns(row.columns, primaryKey) {
+			label = keyLabel(primaryKey, row.values)
+		}
+
+		j := -1
+		switch {
+		case keyed && hasColumns(row.columns, primaryKey):
+			key := rowKey(primaryKey, row.values)
+			for k, actualRow := range actual.rows {
+				if !matched[k] && rowKey(primaryKey, actualRow) == key {
+					j = k
+					break
+				}
+			}
+		case !keyed && ordered:
+			if i < len(actual.rows) {
+				j = i
+			}
+		default:
+			for k, actualRow := range actual.rows {
+				if !matched[k] && len(differentColumns(row, actualRow, loc)) == 0 {
+					j = k
+					break
+				}
+			}
+		}
+
+		if j < 0 {
+			d.missing = append(d.missing, labeledRow{label: label, columns: row.columns, values: row.values, rec: row.rec})
+			continue
+		}
+
+		matched[j] = true
+		matchedOrder = append(matchedOrder, j)
+		matchedLabels = append(matchedLabels, label)
+		if columns := differentColumns(row, actual.rows[j], loc); len(columns) > 0 {
+			d.changed = append(d.changed, changedRow{label: label, expected: row, actual: actual.rows[j], columns: columns})
+		}
+	}
+
+	if !options.subset {
+		for k, actualRow := range actual.rows {
+			if matched[k] {
+				continue
+			}
+			label := fmt.Sprintf("row %d", k+1)
+			if keyed {
+				label = keyLabel(primaryKey, actualRow)
+			}
+			d.unexpected = append(d.unexpected, labeledRow{label: label, columns: actual.columns, values: actualRow})
+		}
+	}
+
+	// 主キーで対応付けた場合、順序の違いは値の差分とは別に報告する
+	if ordered && keyed && !sort.IntsAreSorted(matchedOrder) {
+		actualLabels := make([]string, len(matchedOrder))
+		sorted := append([]int(nil), matchedOrder...)
+		sort.Ints(sorted)
+		for i, k := range sorted {
+			actualLabels[i] = keyLabel(primaryKey, actual.rows[k])
+		}
+		d.order = fmt.Sprintf("expected %s; got %s", strings.Join(matchedLabels, ", "), strings.Join(actualLabels, ", "))
+	}
+
+	return d
+}
+
+// String は差分を表示用の文字列で返す（差分がない場合は空文字）
+// 行の先頭の ~ は値の異なる行、- は欠けた行、+ は余分な行を表し、値の異なるカラムには * を付ける
+func (d *tableDiff) String() string {
+	var counts []string
+	if len(d.changed) > 0 {
+		counts = append(counts, fmt.Sprintf("%d changed", len(d.changed)))
+	}
+	if len(d.missing) > 0 {
+		counts = append(counts, fmt.Sprintf("%d missing", len(d.missing)))
+	}
+	if len(d.unexpected) > 0 {
+		counts = append(counts, fmt.Sprintf("%d unexpected", len(d.unexpected)))
+	}
+	if len(counts) == 0 && len(d.missingColumns) == 0 && d.order == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "table %s does not match expected data", d.table)
+	if len(counts) > 0 {
+		fmt.Fprintf(&b, " (rows: %s)", strings.Join(counts, ", "))
+	}
+
+	for _, col := range d.missingColumns {
+		fmt.Fprintf(&b, "\n  ! column %s does not exist", col)
+	}
+
+	for _, c := range d.changed {
+		fmt.Fprintf(&b, "\n  ~ %s%s", c.label, recordLocation(c.expected.rec))
+		for _, col := range c.expected.columns {
+			if containsString(c.columns, col) {
+				fmt.Fprintf(&b, "\n      * %s: expected %s, got %s", col, formatValue(c.expected.values[col]), formatValue(c.actual[col]))
+			} else {
+				fmt.Fprintf(&b, "\n        %s: %s", col, formatValue(c.expected.values[col]))
+			}
+		}
+	}
+
+	for _, row := range d.missing {
+		fmt.Fprintf(&b, "\n  - %s%s: %s", row.label, recordLocation(row.rec), describeRow(row.columns, row.values))
+	}
+	for _, row := range d.unexpected {
+		fmt.Fprintf(&b, "\n  + %s: %s", row.label, describeRow(row.columns, row.values))
+	}
+
+	if d.order != "" {
+		fmt.Fprintf(&b, "\n  ! rows are not in the expected order: %s", d.order)
+	}
+
+	return b.String()
+}
+
+// recordLocation は期待値の記述位置を " (ファイル名:行番号)" 形式で返す（位置がない場合は空文字）
+func recordLocation(rec *record) string {
+	if rec == nil {
+		return ""
+	}
+	return " (" + rec.location() + ")"
+}
+
+// hasColumns は columns が required のカラムをすべて含むかを判定する
+func hasColumns(columns, required []string) bool {
+	for _, col := range required {
+		if !containsString(columns, col) {
+			return false
+		}
+	}
+	return len(required) > 0
+}
+
+// rowKey は行の主キーの値を対応付け用の文字列で返す
+func rowKey(primaryKey []string, values map[string]interface{}) string {
+	parts := make([]string, len(primaryKey))
+	for i, col := range primaryKey {
+		parts[i] = valueString(values[col])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// keyLabel は行の主キーを id=1 形式で表す
+func keyLabel(primaryKey []string, values map[string]interface{}) string {
+	parts := make([]string, len(primaryKey))
+	for i, col := range primaryKey {
+		parts[i] = col + "=" + formatValue(values[col])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// differentColumns は期待値の行とテーブルの行で値が異なるカラムを返す
+func differentColumns(expected expectedRow, actual map[string]interface{}, loc *time.Location) []string {
+	var columns []string
+	for _, col := range expected.columns {
+		if !valuesEqual(expected.values[col], actual[col], loc) {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+// sortRows は行を主キーの順に並べ替える
+func sortRows(primaryKey []string, rows []map[string]interface{}) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, col := range primaryKey {
+			if c := compareValues(rows[i][col], rows[j][col]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// valuesEqual は期待値と実際の値が等しいかを判定する（タイムゾーンの記述がない日時は loc とみなす）
+// 数値や真偽値はデータベースごとの型の違いを吸収するため文字列表現で比較し、日時は時刻として比較する
+func valuesEqual(expected, actual interface{}, loc *time.Location) bool {
+	if expected == nil || actual == nil {
+		return expected == nil && actual == nil
+	}
+
+	_, expectedIsTime := expected.(time.Time)
+	_, actualIsTime := actual.(time.Time)
+	if expectedIsTime || actualIsTime {
+		e, ok1 := toTime(expected, loc)
+		a, ok2 := toTime(actual, loc)
+		return ok1 && ok2 && e.Equal(a)
+	}
+
+	return valueString(expected) == valueString(actual)
+}
+
+// toTime は日時または日時として解釈できる文字列を time.Time に変換する
+func toTime(value interface{}, loc *time.Location) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		return parseTime(v, loc)
+	default:
+		return time.Time{}, false
+	}
+}
+
+// valueString は比較用に値を文字列で表す（真偽値は 1/0）
+func valueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// compareValues は主キーの並べ替えのために値を比較する（数値は数値として比較する）
+func compareValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	as, bs := valueString(a), valueString(b)
+	af, aErr := strconv.ParseFloat(as, 64)
+	bf, bErr := strconv.ParseFloat(bs, 64)
+	if aErr == nil && bErr == nil {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(as, bs)
+}
+
+// formatValue は差分の表示用に値を表す
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return strconv.Quote(v)
+	case []byte:
+		return strconv.Quote(string(v))
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return valueString(v)
+	}
+}
+
+// describeRow は差分の表示用に行を {カラム: 値, ...} 形式で表す
+func describeRow(columns []string, values map[string]interface{}) string {
+	parts := make([]string, len(columns))
+	for i, col := range columns {
+		parts[i] = col + ": " + formatValue(values[col])
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
@@ -0,0 +1,97 @@
+package yamlfix
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LoadError はフィクスチャの読み込みに失敗した位置を表す（errors.As で取得できる）
+type LoadError struct {
+	SourceFile string // 読み込み元のファイル名（YAMLデータを直接読み込んだ場合は空）
+	Line       int    // YAML上の行番号（不明な場合は0）
+	Column     string // 問題のあるカラム名（カラムに関するエラーの場合）
+	Err        error
+}
+
+// Error はエラーメッセージを返す
+func (e *LoadError) Error() string {
+	var b strings.Builder
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "failed to load fixtures at %s:%d", displayName(e.SourceFile), e.Line)
+	} else {
+		fmt.Fprintf(&b, "failed to load fixtures from %s", displayName(e.SourceFile))
+	}
+	if e.Column != "" {
+		fmt.Fprintf(&b, ": column %s", e.Column)
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	return b.String()
+}
+
+// Unwrap は元のエラーを返す
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// loadErrorf は位置情報付きの LoadError を作成する
+func loadErrorf(filename string, line int, column, format string, args ...interface{}) error {
+	return &LoadError{SourceFile: filename, Line: line, Column: column, Err: fmt.Errorf(format, args...)}
+}
+
+// yamlLinePattern は yaml.v3 の構文エラーに含まれる行番号にマッチする
+var yamlLinePattern = regexp.MustCompile(`^yaml: line (\d+):`)
+
+// yamlErrorLine は yaml.v3 のエラーメッセージから行番号を取り出す（含まれない場合は0）
+func yamlErrorLine(err error) int {
+	m := yamlLinePattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(m[1])
+	return line
+}
+
+// InsertError はレコードの挿入に失敗したレコードとクエリを表す（errors.As で取得できる）
+// 複数行のINSERT文が失敗した場合は1件ずつ挿入し直して特定したレコードを表す（特定できない場合は文の最初のレコード）
+type InsertError struct {
+	Table       string        // テーブル名
+	RecordIndex int           // テーブル内のレコードの位置（0始まり）
+	SourceFile  string        // レコードの読み込み元のファイル名
+	Line        int           // レコードのYAML上の行番号
+	Column      string        // 値の解決や変換に失敗したカラム名（該当する場合）
+	Query       string        // 実行したSQL（実行前に失敗した場合は空）
+	Args        []interface{} // バインドした引数
+	Err         error
+
+	location string // エラーメッセージ用の記述位置（複数行の場合は範囲）
+}
+
+// Error はエラーメッセージを返す
+func (e *InsertError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed to insert record %d of table %s at %s", e.RecordIndex, e.Table, e.location)
+	if e.Column != "" {
+		fmt.Fprintf(&b, ": column %s", e.Column)
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	return b.String()
+}
+
+// Unwrap は元のエラーを返す
+func (e *InsertError) Unwrap() error {
+	return e.Err
+}
+
+// newInsertError は index 番目のレコード rec の挿入エラーを作成する
+func newInsertError(tableName string, index int, rec *record, err error) *InsertError {
+	return &InsertError{
+		Table:       tableName,
+		RecordIndex: index,
+		SourceFile:  rec.source,
+		Line:        rec.line,
+		Err:         err,
+		location:    rec.location(),
+	}
+}
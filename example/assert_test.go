@@ -0,0 +1,179 @@
+package example
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// createBlogTables は users / posts テーブルを作成する
+func createBlogTables(t *testing.T, tx *sql.Tx) {
+	t.Helper()
+	if _, err := tx.Exec(`
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT, created_at DATETIME);
+		CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER, title TEXT, content TEXT, created_at DATETIME);
+	`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestAssertTable はテーブルの内容を期待値のYAMLと比較できることをテストする
+func TestAssertTable(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fixture := yamlfix.NewTestFixture(t, db)
+	fixture.SetupTest("testdata/users.yaml")
+
+	fixture.RunTestWithSetup(
+		func(tx *sql.Tx) { createBlogTables(t, tx) },
+		func(tx *sql.Tx) {
+			fixture.AssertTable(t, "users", `
+- id: 1
+  name: "山田太郎"
+  created_at: 2023-01-01 10:00:00
+- id: 2
+  name: "田中花子"
+  created_at: 2023-01-02 11:00:00
+`)
+
+			tests := []struct {
+				name     string
+				expected string
+				opts     []yamlfix.AssertOption
+				problem  string
+			}{
+				{
+					name:     "値の不一致",
+					expected: "- {id: 1, name: 山田次郎}\n- {id: 2, name: 田中花子}\n",
+					problem:  `* name: expected "山田次郎", got "山田太郎"`,
+				},
+				{
+					name:     "無視したカラム",
+					expected: "- {id: 1, name: 山田次郎}\n- {id: 2, name: 田中花子}\n",
+					opts:     []yamlfix.AssertOption{yamlfix.IgnoreColumns("name")},
+				},
+				{
+					name:     "順序の違い",
+					expected: "- {id: 2}\n- {id: 1}\n",
+					problem:  "rows are not in the expected order: expected id=2, id=1; got id=1, id=2",
+				},
+				{
+					name:     "順序を問わない比較",
+					expected: "- {id: 2}\n- {id: 1}\n",
+					opts:     []yamlfix.AssertOption{yamlfix.IgnoreOrder()},
+				},
+				{
+					name:     "余分な行",
+					expected: "- {id: 2}\n",
+					opts:     []yamlfix.AssertOption{yamlfix.IgnoreOrder()},
+					problem:  `+ id=1: {id: 1, name: "山田太郎"`,
+				},
+				{
+					name:     "部分一致",
+					expected: "- {id: 2}\n",
+					opts:     []yamlfix.AssertOption{yamlfix.Subset()},
+				},
+				{
+					name:     "存在しない行",
+					expected: "- {id: 3}\n",
+					opts:     []yamlfix.AssertOption{yamlfix.Subset()},
+					problem:  "- id=3 (<yaml>:1): {id: 3}",
+				},
+				{
+					name:     "存在しないカラム",
+					expected: "- {id: 1, nickname: taro}\n",
+					opts:     []yamlfix.AssertOption{yamlfix.Subset()},
+					problem:  "! column nickname does not exist",
+				},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					rec := &recordingTB{TB: t}
+					fixture.AssertTable(rec, "users", tt.expected, tt.opts...)
+
+					if tt.problem == "" {
+						if len(rec.errors) != 0 {
+							t.Errorf("expected no error, got: %v", rec.errors)
+						}
+						return
+					}
+					if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], tt.problem) {
+						t.Errorf("expected error containing %q, got: %v", tt.problem, rec.errors)
+					}
+				})
+			}
+		},
+	)
+}
+
+// TestAssertDatabase はディレクトリの期待値のYAMLと複数のテーブルの内容を比較できることをテストする
+func TestAssertDatabase(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fixture := yamlfix.NewTestFixture(t, db)
+	fixture.SetupTest("testdata/users.yaml", "testdata/posts.yaml")
+
+	fixture.RunTestWithSetup(
+		func(tx *sql.Tx) { createBlogTables(t, tx) },
+		func(tx *sql.Tx) {
+			if _, err := tx.Exec(`UPDATE users SET name = '山田次郎' WHERE id = 1`); err != nil {
+				t.Fatal(err)
+			}
+
+			fixture.AssertDatabase(t, "testdata/expected")
+		},
+	)
+}
+
+// TestAssertInSubtests は並列実行されるサブテストのトランザクションからテーブルを読み取って検証できることをテストする
+func TestAssertInSubtests(t *testing.T) {
+	// メモリDBはコネクションごとに独立しているため、サブテストのトランザクション以外からは読み取れない
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	fixture := yamlfix.NewTestFixture(t, db)
+	fixture.SetupTest("testdata/users.yaml")
+
+	for i := range 3 {
+		name := fmt.Sprintf("ユーザー%d", i)
+		fixture.RunWithSetup(t, name,
+			func(t *testing.T, tx *sql.Tx) { createBlogTables(t, tx) },
+			func(t *testing.T, tx *sql.Tx) {
+				if _, err := tx.Exec(`UPDATE users SET name = ? WHERE id = 1`, name); err != nil {
+					t.Fatal(err)
+				}
+				fixture.AssertTable(t, "users", fmt.Sprintf("- {id: 1, name: %s}\n- {id: 2, name: 田中花子}\n", name), yamlfix.ReadFrom(tx))
+			},
+		)
+	}
+
+	t.Run("session", func(t *testing.T) {
+		session, err := fixture.Freeze().NewSession(t.Context(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer session.Rollback()
+
+		createBlogTables(t, session.Tx())
+		if err := session.InsertFixtures(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+		session.AssertTable(t, "users", "- {id: 1, name: 山田太郎}\n- {id: 2, name: 田中花子}\n")
+	})
+}
@@ -0,0 +1,68 @@
+package example
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestBatchInsert は複数行INSERTで大量のレコードを挿入できることをテストする
+func TestBatchInsert(t *testing.T) {
+	const total = 1000
+
+	var b strings.Builder
+	for i := 1; i <= total; i++ {
+		fmt.Fprintf(&b, "- id: %d\n  code: \"C%04d\"\n", i, i)
+	}
+
+	tests := map[string]struct {
+		batchSize int
+	}{
+		"デフォルトのバッチサイズ": {batchSize: 0},
+		"端数が出るバッチサイズ":  {batchSize: 7},
+		"1件ずつ挿入":       {batchSize: 1},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			db.SetMaxOpenConns(1) // メモリDBを単一コネクションで共有する
+
+			if _, err := db.Exec(`CREATE TABLE codes (id INTEGER PRIMARY KEY, code TEXT NOT NULL)`); err != nil {
+				t.Fatal(err)
+			}
+
+			fixture := yamlfix.New(yamlfix.Config{DB: db, BatchSize: tt.batchSize})
+			if err := fixture.LoadFromYAMLWithFilename([]byte(b.String()), "codes.yaml"); err != nil {
+				t.Fatal(err)
+			}
+			if err := fixture.InsertFixtures(); err != nil {
+				t.Fatal(err)
+			}
+
+			var count, sum int
+			if err := db.QueryRow("SELECT COUNT(*), SUM(id) FROM codes").Scan(&count, &sum); err != nil {
+				t.Fatal(err)
+			}
+			if count != total || sum != total*(total+1)/2 {
+				t.Errorf("expected: count=%d, sum=%d, got: count=%d, sum=%d", total, total*(total+1)/2, count, sum)
+			}
+
+			var code string
+			if err := db.QueryRow("SELECT code FROM codes WHERE id = ?", total).Scan(&code); err != nil {
+				t.Fatal(err)
+			}
+			if want := fmt.Sprintf("C%04d", total); code != want {
+				t.Errorf("expected: %s, got: %s", want, code)
+			}
+		})
+	}
+}
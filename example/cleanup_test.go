@@ -0,0 +1,94 @@
+package example
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// recordingTB はテストの失敗を記録する testing.TB（Fatal 系は埋め込んだ testing.TB に委ねる）
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+// Errorf は失敗メッセージを記録する
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// Error は失敗メッセージを記録する
+func (r *recordingTB) Error(args ...any) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+// TestCleanupReportsTransactionMisuse はトランザクションの誤用がテストの失敗として報告されることをテストする
+func TestCleanupReportsTransactionMisuse(t *testing.T) {
+	tests := map[string]struct {
+		run func(t *testing.T, fixture *yamlfix.TestFixture)
+	}{
+		"テストコードがコミットした": {
+			run: func(t *testing.T, fixture *yamlfix.TestFixture) {
+				fixture.RunTest(func(tx *sql.Tx) {
+					if err := tx.Commit(); err != nil {
+						t.Fatal(err)
+					}
+				})
+			},
+		},
+		"トランザクションを開いたまま終了した": {
+			run: func(t *testing.T, fixture *yamlfix.TestFixture) {
+				if err := fixture.BeginTransaction(); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			rec := &recordingTB{}
+			// t.Cleanup は内側のテスト終了時に実行される
+			t.Run("inner", func(t *testing.T) {
+				rec.TB = t
+				tt.run(t, yamlfix.NewTestFixture(rec, db))
+			})
+
+			if len(rec.errors) != 1 {
+				t.Errorf("expected: 1 error, got: %v", rec.errors)
+			}
+		})
+	}
+}
+
+// BenchmarkInsertFixtures はベンチマークからフィクスチャを利用できることを確認する
+func BenchmarkInsertFixtures(b *testing.B) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	fixture := yamlfix.NewTestFixture(b, db)
+	fixture.SetupTest("testdata/users.yaml")
+
+	for b.Loop() {
+		fixture.RunTestWithSetup(
+			func(tx *sql.Tx) {
+				if _, err := tx.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT, created_at TEXT)`); err != nil {
+					b.Fatal(err)
+				}
+			},
+			func(tx *sql.Tx) {},
+		)
+	}
+}
@@ -0,0 +1,79 @@
+package example
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestHeterogeneousColumns はレコードごとにカラム構成が異なる場合の挿入をテストする
+func TestHeterogeneousColumns(t *testing.T) {
+	const data = `
+users:
+  - id: 1
+    name: "山田太郎"
+  - id: 2
+    name: "田中花子"
+    status: "inactive"
+    nickname: "はなこ"
+`
+
+	tests := map[string]struct {
+		policy     yamlfix.MissingColumnPolicy
+		wantStatus sql.NullString
+	}{
+		"欠けたカラムはデフォルト値": {policy: yamlfix.OmitMissingColumns, wantStatus: sql.NullString{String: "active", Valid: true}},
+		"欠けたカラムはNULL":   {policy: yamlfix.NullMissingColumns, wantStatus: sql.NullString{}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			db.SetMaxOpenConns(1) // メモリDBを単一コネクションで共有する
+
+			_, err = db.Exec(`
+				CREATE TABLE users (
+					id INTEGER PRIMARY KEY,
+					name TEXT NOT NULL,
+					status TEXT DEFAULT 'active',
+					nickname TEXT
+				)
+			`)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fixture := yamlfix.New(yamlfix.Config{DB: db, MissingColumns: tt.policy})
+			if err := fixture.LoadFromYAML([]byte(data)); err != nil {
+				t.Fatal(err)
+			}
+			if err := fixture.InsertFixtures(); err != nil {
+				t.Fatal(err)
+			}
+
+			// 1件目は status を持たない
+			var status sql.NullString
+			if err := db.QueryRow("SELECT status FROM users WHERE id = 1").Scan(&status); err != nil {
+				t.Fatal(err)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status - expected: %v, got: %v", tt.wantStatus, status)
+			}
+
+			// 2件目の追加カラムは失われない
+			var nickname string
+			if err := db.QueryRow("SELECT nickname FROM users WHERE id = 2").Scan(&nickname); err != nil {
+				t.Fatal(err)
+			}
+			if nickname != "はなこ" {
+				t.Errorf("nickname - expected: はなこ, got: %s", nickname)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package example
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestInsertFixturesContextCanceled はキャンセル済みのコンテキストで挿入が中断されることをテストする
+func TestInsertFixturesContextCanceled(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1) // メモリDBを単一コネクションで共有する
+
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT, created_at TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := yamlfix.New(yamlfix.Config{DB: db, AutoRollback: true})
+	if err := fixture.LoadFromFile("testdata/users.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	if err := fixture.BeginTx(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	defer fixture.CleanUp()
+
+	cancel()
+	if err := fixture.InsertFixturesContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected: context.Canceled, got: %v", err)
+	}
+}
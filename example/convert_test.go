@@ -0,0 +1,160 @@
+package example
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestValueConversion はJSON・バイト列・10進数・日時の変換と ValueConverter をテストする
+func TestValueConversion(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE products (id INTEGER PRIMARY KEY, code TEXT, attrs TEXT, tags TEXT, image BLOB, price TEXT, released_at TIMESTAMP, released_on TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := yamlfix.New(yamlfix.Config{
+		DB:           db,
+		TimeLocation: time.FixedZone("JST", 9*60*60),
+		ValueConverter: func(table, column string, value interface{}) (interface{}, error) {
+			if table == "products" && column == "code" {
+				return strings.ToUpper(value.(string)), nil
+			}
+			return value, nil
+		},
+	})
+	err = fixture.LoadFromYAML([]byte(`
+products:
+  - id: 1
+    code: "abc-001"
+    attrs: {color: "red", size: {width: 10}}
+    tags: ["new", "sale"]
+    image: !!binary aGVsbG8=
+    price: !decimal 1234.5678901234567890
+    released_at: 2023-01-01 10:00:00
+    released_on: "2023-01-01"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.InsertFixtures(); err != nil {
+		t.Fatal(err)
+	}
+
+	var code, attrs, tags, price string
+	var image []byte
+	var releasedAt time.Time
+	err = db.QueryRow(`SELECT code, attrs, tags, image, price, released_at FROM products`).Scan(&code, &attrs, &tags, &image, &price, &releasedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code != "ABC-001" {
+		t.Errorf("expected converted code, got: %s", code)
+	}
+	if attrs != `{"color":"red","size":{"width":10}}` {
+		t.Errorf("expected JSON object, got: %s", attrs)
+	}
+	if tags != `["new","sale"]` {
+		t.Errorf("expected JSON array, got: %s", tags)
+	}
+	if string(image) != "hello" {
+		t.Errorf("expected decoded binary, got: %q", image)
+	}
+	if price != "1234.5678901234567890" {
+		t.Errorf("expected exact decimal, got: %s", price)
+	}
+	if want := time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC); !releasedAt.Equal(want) {
+		t.Errorf("expected %v, got: %v", want, releasedAt)
+	}
+}
+
+// TestTimestampWithoutLocation は TimeLocation を指定しない場合に日時を記述した文字列のまま挿入することをテストする
+func TestTimestampWithoutLocation(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE events (id INTEGER PRIMARY KEY, quoted TEXT, unquoted TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := yamlfix.New(yamlfix.Config{DB: db})
+	if err := fixture.LoadFromYAML([]byte("events:\n  - id: 1\n    quoted: \"2023-01-01 10:00:00\"\n    unquoted: 2023-01-01 10:00:00\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.InsertFixtures(); err != nil {
+		t.Fatal(err)
+	}
+
+	var quoted, unquoted string
+	if err := db.QueryRow(`SELECT quoted, unquoted FROM events`).Scan(&quoted, &unquoted); err != nil {
+		t.Fatal(err)
+	}
+	if quoted != unquoted {
+		t.Errorf("expected the same value regardless of quoting, got: %s and %s", quoted, unquoted)
+	}
+}
+
+// TestTimeLocationRoundTrip は TimeLocation を指定して挿入した日時を同じYAMLで検証できることをテストする
+func TestTimeLocationRoundTrip(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE events (id INTEGER PRIMARY KEY, started_at TEXT, held_on TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := `
+- id: 1
+  started_at: "2023-01-01 10:00:00"
+  held_on: "2023-01-01"
+`
+	fixture := yamlfix.NewTestFixtureWithConfig(t, yamlfix.Config{DB: db, TimeLocation: time.FixedZone("JST", 9*60*60)})
+	if err := fixture.LoadFromYAML([]byte("events:" + rows)); err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.InsertFixtures(); err != nil {
+		t.Fatal(err)
+	}
+
+	var startedAt, heldOn string
+	if err := db.QueryRow(`SELECT started_at, held_on FROM events`).Scan(&startedAt, &heldOn); err != nil {
+		t.Fatal(err)
+	}
+	if startedAt != "2023-01-01 10:00:00+09:00" || heldOn != "2023-01-01 00:00:00+09:00" {
+		t.Errorf("expected local times with offset, got: %s and %s", startedAt, heldOn)
+	}
+
+	// 日付関数はオフセットをUTCに換算する
+	var utc string
+	if err := db.QueryRow(`SELECT datetime(started_at) FROM events`).Scan(&utc); err != nil {
+		t.Fatal(err)
+	}
+	if utc != "2023-01-01 01:00:00" {
+		t.Errorf("expected 2023-01-01 01:00:00, got: %s", utc)
+	}
+
+	rec := &recordingTB{TB: t}
+	fixture.AssertTable(rec, "events", rows)
+	if len(rec.errors) != 0 {
+		t.Errorf("expected inserted times to match, got: %v", rec.errors)
+	}
+}
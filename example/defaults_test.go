@@ -0,0 +1,147 @@
+package example
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestDefaults はデフォルト値がレコードにマージされ、テンプレートがレコードごとに評価されることをテストする
+func TestDefaults(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fixture := yamlfix.NewTestFixtureWithConfig(t, yamlfix.Config{DB: db, Template: true})
+	fixture.SetupTest("testdata/defaults/users.yaml")
+
+	fixture.RunTestWithSetup(
+		func(tx *sql.Tx) {
+			_, err := tx.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT, status TEXT, is_admin INTEGER)`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		},
+		func(tx *sql.Tx) {
+			rows, err := tx.Query(`SELECT email, status, is_admin FROM users ORDER BY id`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+
+			var got []string
+			for rows.Next() {
+				var email, status, isAdmin string
+				if err := rows.Scan(&email, &status, &isAdmin); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, email+"/"+status+"/"+isAdmin)
+			}
+
+			want := "user1@example.com/active/1,hanako@example.com/active/0,user3@example.com/suspended/0"
+			if strings.Join(got, ",") != want {
+				t.Errorf("expected: %s, got: %s", want, strings.Join(got, ","))
+			}
+		},
+	)
+}
+
+// TestDefaultsMultiTable は複数テーブル形式でテーブルごとにデフォルト値を指定できることをテストする
+func TestDefaultsMultiTable(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`
+		CREATE TABLE users (id INTEGER PRIMARY KEY, status TEXT);
+		CREATE TABLE posts (id INTEGER PRIMARY KEY, status TEXT);
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := yamlfix.New(yamlfix.Config{DB: db})
+	err = fixture.LoadFromYAML([]byte(`
+users:
+  - _defaults:
+      status: "active"
+  - id: 1
+posts:
+  - _defaults:
+      status: "draft"
+  - id: 1
+  - id: 2
+    status: "published"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.InsertFixtures(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	err = db.QueryRow(`SELECT (SELECT group_concat(status) FROM users) || ',' || (SELECT group_concat(status) FROM (SELECT status FROM posts ORDER BY id))`).Scan(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "active,draft,published" {
+		t.Errorf("expected: active,draft,published, got: %s", got)
+	}
+}
+
+// TestDefaultsNotFirst は _defaults がテーブルの先頭以外にある場合にエラーになることをテストする
+func TestDefaultsNotFirst(t *testing.T) {
+	fixture := yamlfix.New(yamlfix.Config{})
+	err := fixture.LoadFromYAMLWithFilename([]byte("- id: 1\n- _defaults:\n    status: active\n"), "users.yaml")
+	if err == nil || !strings.Contains(err.Error(), "users.yaml:2") {
+		t.Errorf("expected error with location, got: %v", err)
+	}
+}
+
+// TestDefaultsWithExtends は継承したレコードを上書きする場合にデフォルト値が継承した値を上書きしないことをテストする
+func TestDefaultsWithExtends(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fixture := yamlfix.NewTestFixture(t, db)
+	err = fixture.LoadFromFS(fstest.MapFS{
+		"base.yaml": {Data: []byte("users:\n  - {id: 1, name: 山田太郎, status: suspended}\n")},
+		"child.yaml": {Data: []byte(`
+extends: base.yaml
+users:
+  - _defaults:
+      status: active
+  - {id: 1, name: 山田次郎}
+  - {id: 2, name: 田中花子}
+`)},
+	}, "child.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixture.RunTestWithSetup(
+		func(tx *sql.Tx) {
+			if _, err := tx.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, status TEXT)`); err != nil {
+				t.Fatal(err)
+			}
+		},
+		func(tx *sql.Tx) {
+			fixture.AssertTable(t, "users", `
+- {id: 1, name: 山田次郎, status: suspended}
+- {id: 2, name: 田中花子, status: active}
+`)
+		},
+	)
+}
@@ -0,0 +1,115 @@
+package example
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestDialect は各方言のプレースホルダーと識別子のクォートをテストする
+func TestDialect(t *testing.T) {
+	tests := map[string]struct {
+		dialect     yamlfix.Dialect
+		placeholder string
+		quoted      string
+	}{
+		"MySQL":      {dialect: yamlfix.MySQLDialect{}, placeholder: "?", quoted: "`public`.`users`"},
+		"PostgreSQL": {dialect: yamlfix.PostgreSQLDialect{}, placeholder: "$2", quoted: `"public"."users"`},
+		"SQLite":     {dialect: yamlfix.SQLiteDialect{}, placeholder: "?", quoted: `"public"."users"`},
+		"SQL Server": {dialect: yamlfix.SQLServerDialect{}, placeholder: "@p2", quoted: "[public].[users]"},
+		"Oracle":     {dialect: yamlfix.OracleDialect{}, placeholder: ":2", quoted: `"public"."users"`},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tt.dialect.Placeholder(2); got != tt.placeholder {
+				t.Errorf("placeholder - expected: %s, got: %s", tt.placeholder, got)
+			}
+			if got := tt.dialect.QuoteIdentifier("public.users"); got != tt.quoted {
+				t.Errorf("quoted - expected: %s, got: %s", tt.quoted, got)
+			}
+		})
+	}
+}
+
+// TestConvertValue は各方言が真偽値と日時をデータベースが扱える形式に変換することをテストする
+func TestConvertValue(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	releasedAt := time.Date(2023, 1, 1, 10, 0, 0, 0, jst)
+
+	tests := map[string]struct {
+		dialect yamlfix.Dialect
+		boolean interface{}
+		time    interface{}
+	}{
+		"MySQL":      {dialect: yamlfix.MySQLDialect{}, boolean: 1, time: releasedAt},
+		"PostgreSQL": {dialect: yamlfix.PostgreSQLDialect{}, boolean: true, time: releasedAt},
+		"SQLite":     {dialect: yamlfix.SQLiteDialect{}, boolean: 1, time: "2023-01-01 10:00:00+09:00"},
+		"SQL Server": {dialect: yamlfix.SQLServerDialect{}, boolean: 1, time: releasedAt.UTC()},
+		"Oracle":     {dialect: yamlfix.OracleDialect{}, boolean: 1, time: releasedAt},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tt.dialect.ConvertValue(true); got != tt.boolean {
+				t.Errorf("bool - expected: %v, got: %v", tt.boolean, got)
+			}
+			if got := tt.dialect.ConvertValue(releasedAt); got != tt.time {
+				t.Errorf("time - expected: %v, got: %v", tt.time, got)
+			}
+		})
+	}
+}
+
+// TestDetectDialect はドライバから方言を自動判定できることをテストする
+func TestDetectDialect(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if got := yamlfix.DetectDialect(db).Name(); got != "sqlite" {
+		t.Errorf("expected: sqlite, got: %s", got)
+	}
+}
+
+// TestReservedWordColumn は予約語のカラム名がクォートされて挿入できることをテストする
+func TestReservedWordColumn(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1) // メモリDBを単一コネクションで共有する
+
+	fixture := yamlfix.New(yamlfix.Config{DB: db})
+	err = fixture.LoadFromYAML([]byte(`
+groups:
+  - id: 1
+    order: 10
+    active: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec(`CREATE TABLE "groups" (id INTEGER PRIMARY KEY, "order" INTEGER, active INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fixture.InsertFixtures(); err != nil {
+		t.Fatal(err)
+	}
+
+	var order, active int
+	if err := db.QueryRow(`SELECT "order", active FROM "groups" WHERE id = 1`).Scan(&order, &active); err != nil {
+		t.Fatal(err)
+	}
+	if order != 10 || active != 1 {
+		t.Errorf("expected: order=10, active=1, got: order=%d, active=%d", order, active)
+	}
+}
@@ -0,0 +1,38 @@
+package example
+
+import (
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+)
+
+// TestDiffTables は行を主キーで対応付け、値の異なる行・欠けた行・余分な行を表示することをテストする
+func TestDiffTables(t *testing.T) {
+	expected := []map[string]interface{}{
+		{"id": 1, "name": "山田次郎", "email": "yamada@example.com"},
+		{"id": 3, "name": "鈴木一郎"},
+	}
+	actual := []map[string]interface{}{
+		{"id": int64(2), "name": "田中花子", "email": nil},
+		{"id": int64(1), "name": "山田太郎", "email": []byte("yamada@example.com")},
+	}
+
+	want := `table users does not match expected data (rows: 1 changed, 1 missing, 1 unexpected)
+  ~ id=1
+        id: 1
+        email: "yamada@example.com"
+      * name: expected "山田次郎", got "山田太郎"
+  - id=3: {id: 3, name: "鈴木一郎"}
+  + id=2: {id: 2, email: NULL, name: "田中花子"}`
+
+	if got := yamlfix.DiffTables("users", nil, expected, actual); got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := yamlfix.DiffTables("users", []string{"id"}, expected[:1], actual[1:]); got == "" {
+		t.Errorf("expected diff for changed row")
+	}
+	if got := yamlfix.DiffTables("users", []string{"id"}, actual, actual); got != "" {
+		t.Errorf("expected no diff, got:\n%s", got)
+	}
+}
@@ -0,0 +1,144 @@
+package example
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestLoadError は読み込みエラーを errors.As で LoadError として取得できることをテストする
+func TestLoadError(t *testing.T) {
+	tests := []struct {
+		name   string
+		yaml   string
+		line   int
+		column string
+	}{
+		{
+			name: "レコードがマッピングではない",
+			yaml: "users:\n  - id: 1\n  - 2\n",
+			line: 3,
+		},
+		{
+			name:   "カラムの重複",
+			yaml:   "users:\n  - id: 1\n    name: a\n    name: b\n",
+			line:   4,
+			column: "name",
+		},
+		{
+			name: "YAMLの構文エラー",
+			yaml: "users:\n  - id: 1\n    name: \"a\n",
+			line: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := yamlfix.New(yamlfix.Config{})
+			err := fixture.LoadFromYAMLWithFilename([]byte(tt.yaml), "users.yaml")
+
+			var loadErr *yamlfix.LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("expected LoadError, got: %v", err)
+			}
+			if loadErr.SourceFile != "users.yaml" || loadErr.Line != tt.line || loadErr.Column != tt.column {
+				t.Errorf("expected users.yaml:%d column %q, got: %s:%d column %q", tt.line, tt.column, loadErr.SourceFile, loadErr.Line, loadErr.Column)
+			}
+		})
+	}
+}
+
+// TestInsertError は複数行のINSERT文が失敗した場合も原因のレコードを InsertError として取得できることをテストする
+func TestInsertError(t *testing.T) {
+	for _, inTx := range []bool{false, true} {
+		t.Run(fmt.Sprintf("transaction=%v", inTx), func(t *testing.T) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			db.SetMaxOpenConns(1)
+
+			if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`); err != nil {
+				t.Fatal(err)
+			}
+
+			fixture := yamlfix.New(yamlfix.Config{DB: db})
+			err = fixture.LoadFromYAMLWithFilename([]byte("users:\n  - id: 1\n    name: a\n  - id: 2\n    name: b\n  - id: 3\n    name: null\n"), "users.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if inTx {
+				if err := fixture.BeginTransaction(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = fixture.InsertFixtures()
+			var insertErr *yamlfix.InsertError
+			if !errors.As(err, &insertErr) {
+				t.Fatalf("expected InsertError, got: %v", err)
+			}
+			if insertErr.Table != "users" || insertErr.RecordIndex != 2 || insertErr.SourceFile != "users.yaml" || insertErr.Line != 6 {
+				t.Errorf("unexpected error location: %+v", insertErr)
+			}
+			if insertErr.Query == "" || len(insertErr.Args) != 2 || insertErr.Args[0] != 3 {
+				t.Errorf("expected query and args of the failed record, got: %q %v", insertErr.Query, insertErr.Args)
+			}
+
+			// 原因の特定のために挿入し直したレコードは残らず、トランザクションは引き続き使用できる
+			if inTx {
+				if err := fixture.CommitTransaction(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var count int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			if count != 0 {
+				t.Errorf("expected no rows after the failed insert, got %d", count)
+			}
+		})
+	}
+}
+
+// TestInsertErrorColumn は値の変換に失敗したカラムが InsertError に含まれることをテストする
+func TestInsertErrorColumn(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := func(table, column string, value interface{}) (interface{}, error) {
+		if column == "name" {
+			return nil, fmt.Errorf("unsupported value %v", value)
+		}
+		return value, nil
+	}
+
+	fixture := yamlfix.New(yamlfix.Config{DB: db, ValueConverter: converter})
+	if err := fixture.LoadFromYAMLWithFilename([]byte("users:\n  - id: 1\n    name: a\n"), "users.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = fixture.InsertFixtures()
+	var insertErr *yamlfix.InsertError
+	if !errors.As(err, &insertErr) {
+		t.Fatalf("expected InsertError, got: %v", err)
+	}
+	if insertErr.Column != "name" || insertErr.RecordIndex != 0 || insertErr.Line != 2 {
+		t.Errorf("unexpected error location: %+v", insertErr)
+	}
+}
@@ -0,0 +1,79 @@
+package example
+
+import (
+	"database/sql"
+	"embed"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//go:embed testdata/users.yaml testdata/posts.yaml
+var embeddedFixtures embed.FS
+
+// TestLoadFromFS は fs.FS からグロブに一致するファイルとディレクトリを読み込めることをテストする
+func TestLoadFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fixtures/users.yaml":        {Data: []byte("- id: 1\n  name: \"山田太郎\"\n")},
+		"fixtures/posts.yml":         {Data: []byte("- id: 1\n  user_id: 1\n")},
+		"fixtures/README.md":         {Data: []byte("not a fixture")},
+		"fixtures/master/tags.yaml":  {Data: []byte("- id: 1\n  name: go\n")},
+		"fixtures/master/roles.yaml": {Data: []byte("- id: 1\n  name: admin\n")},
+	}
+
+	fixture := yamlfix.New(yamlfix.Config{})
+	if err := fixture.LoadFromFS(fsys, "fixtures/*.yaml", "fixtures/master"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"users", "roles", "tags"}
+	if got := fixture.Tables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected: %v, got: %v", want, got)
+	}
+}
+
+// TestLoadFromFSNoMatch はパターンに一致するファイルがない場合にエラーになることをテストする
+func TestLoadFromFSNoMatch(t *testing.T) {
+	fixture := yamlfix.New(yamlfix.Config{})
+	err := fixture.LoadFromFS(fstest.MapFS{}, "fixtures/*.yaml")
+	if err == nil || !strings.Contains(err.Error(), "fixtures/*.yaml") {
+		t.Errorf("expected no match error, got: %v", err)
+	}
+}
+
+// TestConfigFS は Config.FS を指定すると SetupTest が埋め込みファイルから読み込むことをテストする
+func TestConfigFS(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fixture := yamlfix.NewTestFixtureWithConfig(t, yamlfix.Config{DB: db, FS: embeddedFixtures})
+	fixture.SetupTest("testdata/users.yaml", "testdata/posts.yaml")
+
+	fixture.RunTestWithSetup(
+		func(tx *sql.Tx) {
+			_, err := tx.Exec(`
+				CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT, created_at TEXT);
+				CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER, title TEXT, content TEXT, created_at TEXT);
+			`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		},
+		func(tx *sql.Tx) {
+			var count int
+			if err := tx.QueryRow(`SELECT COUNT(*) FROM posts`).Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			if count != 2 {
+				t.Errorf("expected 2 posts, got %d", count)
+			}
+		},
+	)
+}
@@ -0,0 +1,154 @@
+package example
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestIncludeAndExtends は継承したレコードをキーで上書き・削除できることをテストする
+func TestIncludeAndExtends(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fixture := yamlfix.NewTestFixture(t, db)
+	fixture.SetupTest("testdata/include/child.yaml")
+
+	if got, want := fixture.Tables(), []string{"users", "posts", "tags"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected tables: %v, got: %v", want, got)
+	}
+
+	fixture.RunTestWithSetup(
+		func(tx *sql.Tx) {
+			_, err := tx.Exec(`
+				CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT);
+				CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER, title TEXT);
+				CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT);
+			`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		},
+		func(tx *sql.Tx) {
+			rows, err := tx.Query(`SELECT id, name, email FROM users ORDER BY id`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+
+			var got []string
+			for rows.Next() {
+				var id int
+				var name, email string
+				if err := rows.Scan(&id, &name, &email); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, name+"/"+email)
+			}
+
+			want := "山田太郎/yamada@example.com,田中花子（退会済み）/tanaka@example.com,鈴木一郎/suzuki@example.com"
+			if strings.Join(got, ",") != want {
+				t.Errorf("expected users: %s, got: %s", want, strings.Join(got, ","))
+			}
+
+			var posts, tags int
+			if err := tx.QueryRow(`SELECT (SELECT COUNT(*) FROM posts), (SELECT COUNT(*) FROM tags)`).Scan(&posts, &tags); err != nil {
+				t.Fatal(err)
+			}
+			if posts != 1 || tags != 1 {
+				t.Errorf("expected 1 post and 1 tag, got %d posts and %d tags", posts, tags)
+			}
+		},
+	)
+}
+
+// TestIncludeCycle は循環する include がエラーになることをテストする
+func TestIncludeCycle(t *testing.T) {
+	fixture := yamlfix.New(yamlfix.Config{})
+	err := fixture.LoadFromFile("testdata/include/cycle/a.yaml")
+	if err == nil || !strings.Contains(err.Error(), "include cycle detected: testdata/include/cycle/a.yaml -> testdata/include/cycle/b.yaml -> testdata/include/cycle/a.yaml") {
+		t.Errorf("expected include cycle error, got: %v", err)
+	}
+}
+
+// TestDeleteUnknownRecord は継承したレコードに一致しない削除指定がエラーになることをテストする
+func TestDeleteUnknownRecord(t *testing.T) {
+	fixture := yamlfix.New(yamlfix.Config{})
+	err := fixture.LoadFromYAMLWithFilename([]byte("users:\n  - id: 9\n    _delete: true\n"), "users.yaml")
+	if err == nil || !strings.Contains(err.Error(), "users.yaml:2") {
+		t.Errorf("expected delete error with location, got: %v", err)
+	}
+}
+
+// TestIncludeFromFS は include / extends のファイルを読み込み元と同じ fs.FS から読み込むことをテストする
+func TestIncludeFromFS(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fixture := yamlfix.NewTestFixture(t, db)
+	err = fixture.LoadFromFS(fstest.MapFS{
+		"fx/base.yaml":  {Data: []byte("users:\n  - id: 1\n    name: \"山田太郎\"\n")},
+		"fx/child.yaml": {Data: []byte("extends: base.yaml\nusers:\n  - id: 2\n    name: \"田中花子\"\n")},
+	}, "fx/child.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixture.RunTestWithSetup(
+		func(tx *sql.Tx) {
+			if _, err := tx.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`); err != nil {
+				t.Fatal(err)
+			}
+		},
+		func(tx *sql.Tx) {
+			fixture.AssertTable(t, "users", "- {id: 1, name: 山田太郎}\n- {id: 2, name: 田中花子}\n")
+		},
+	)
+}
+
+// TestRecordsInSameFile は同じファイル内のレコードをキーが同じでもマージせず、ラベルの重複をエラーにすることをテストする
+func TestRecordsInSameFile(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER, name TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := yamlfix.New(yamlfix.Config{DB: db})
+	if err := fixture.LoadFromYAMLWithFilename([]byte("- {id: 1, name: 山田太郎}\n- {id: 1, name: 田中花子}\n"), "users.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.InsertFixtures(); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 rows, got %d", count)
+	}
+
+	fixture = yamlfix.New(yamlfix.Config{})
+	err = fixture.LoadFromYAMLWithFilename([]byte("- {_label: yamada, id: 1}\n- {_label: yamada, id: 2}\n"), "users.yaml")
+	if err == nil || !strings.Contains(err.Error(), "users.yaml:2") || !strings.Contains(err.Error(), "label yamada is already defined at line 1") {
+		t.Errorf("expected duplicate label error, got: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package example
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestFixtureLayers はセーブポイントで積んだレイヤーがケースごとにロールバックされることをテストする
+func TestFixtureLayers(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fixture := yamlfix.NewTestFixture(t, db)
+	fixture.SetupTest("testdata/users.yaml")
+
+	tests := map[string]struct {
+		layers    []string
+		wantUsers int
+		wantPosts int
+	}{
+		"ベースのみ":      {layers: nil, wantUsers: 2, wantPosts: 0},
+		"ユーザーを追加":    {layers: []string{"testdata/layers/users.yaml"}, wantUsers: 3, wantPosts: 0},
+		"投稿とユーザーを追加": {layers: []string{"testdata/posts.yaml", "testdata/layers/users.yaml"}, wantUsers: 3, wantPosts: 2},
+	}
+
+	fixture.RunTestWithSetup(
+		func(tx *sql.Tx) {
+			createUsersTable(tx, t)
+			_, err := tx.Exec(`
+				CREATE TABLE posts (
+					id INTEGER PRIMARY KEY,
+					user_id INTEGER NOT NULL,
+					title TEXT NOT NULL,
+					content TEXT,
+					created_at TEXT
+				)
+			`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		},
+		func(tx *sql.Tx) {
+			for name, tt := range tests {
+				fixture.RunLayer(t, name, tt.layers, func(t *testing.T, tx *sql.Tx) {
+					var users, posts int
+					if err := tx.QueryRow("SELECT COUNT(*) FROM users").Scan(&users); err != nil {
+						t.Fatal(err)
+					}
+					if err := tx.QueryRow("SELECT COUNT(*) FROM posts").Scan(&posts); err != nil {
+						t.Fatal(err)
+					}
+					if users != tt.wantUsers || posts != tt.wantPosts {
+						t.Errorf("expected: users=%d, posts=%d, got: users=%d, posts=%d", tt.wantUsers, tt.wantPosts, users, posts)
+					}
+				})
+			}
+
+			// レイヤーを取り除いた後はベースのフィクスチャのみが残る
+			var users int
+			if err := tx.QueryRow("SELECT COUNT(*) FROM users").Scan(&users); err != nil {
+				t.Fatal(err)
+			}
+			if users != 2 {
+				t.Errorf("expected: 2, got: %d", users)
+			}
+		},
+	)
+}
@@ -0,0 +1,117 @@
+package example
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestTableDeclarationOrder はテーブルがYAMLの記述順で登録されることをテストする
+func TestTableDeclarationOrder(t *testing.T) {
+	data := []byte(`
+tags:
+  - id: 1
+users:
+  - id: 1
+comments:
+  - id: 1
+authors:
+  - id: 1
+`)
+	want := []string{"tags", "users", "comments", "authors"}
+
+	// マップの反復順序に依存しないことを複数回の読み込みで確認する
+	for i := 0; i < 20; i++ {
+		fixture := yamlfix.New(yamlfix.Config{})
+		if err := fixture.LoadFromYAML(data); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := fixture.Tables(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected: %v, got: %v", want, got)
+		}
+	}
+}
+
+// TestInvalidRecord はレコードがマッピングでない場合に位置情報付きのエラーになることをテストする
+func TestInvalidRecord(t *testing.T) {
+	fixture := yamlfix.New(yamlfix.Config{})
+	err := fixture.LoadFromYAMLWithFilename([]byte(`
+- id: 1
+  name: "山田太郎"
+- "invalid"
+`), "users.yaml")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "users.yaml:4") {
+		t.Errorf("expected location users.yaml:4 in error, got: %v", err)
+	}
+}
+
+// TestAnchorsAndMergeKeys はエイリアスとマージキーで記述したレコードを挿入できることをテストする
+func TestAnchorsAndMergeKeys(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, role TEXT, active INTEGER);
+		CREATE TABLE admins (id INTEGER PRIMARY KEY, name TEXT, role TEXT, active INTEGER);
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := yamlfix.New(yamlfix.Config{DB: db})
+	err = fixture.LoadFromYAML([]byte(`
+users:
+  - &admin
+    id: 1
+    name: "山田太郎"
+    role: admin
+    active: true
+  - <<: *admin
+    id: 2
+    name: "田中花子"
+  - <<: [{role: guest, name: "上書きされない"}, *admin]
+    id: 3
+    name: "鈴木一郎"
+admins:
+  - *admin
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.InsertFixtures(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query(`SELECT id, name, role, active FROM users UNION ALL SELECT id, name, role, active FROM admins`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var id, active int
+		var name, role string
+		if err := rows.Scan(&id, &name, &role, &active); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d/%s/%s/%d", id, name, role, active))
+	}
+
+	want := []string{"1/山田太郎/admin/1", "2/田中花子/admin/1", "3/鈴木一郎/guest/1", "1/山田太郎/admin/1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected: %v, got: %v", want, got)
+	}
+}
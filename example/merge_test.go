@@ -0,0 +1,118 @@
+package example
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestDuplicateTableError は同じテーブルを複数のファイルで定義した場合に両方のファイル名を含むエラーになることをテストする
+func TestDuplicateTableError(t *testing.T) {
+	fixture := yamlfix.New(yamlfix.Config{})
+	if err := fixture.LoadFromFile("testdata/users.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := fixture.LoadFromFile("testdata/multi_table.yaml")
+	if err == nil {
+		t.Fatal("expected duplicate table error")
+	}
+	for _, want := range []string{"users", "testdata/users.yaml", "testdata/multi_table.yaml"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %s, got: %v", want, err)
+		}
+	}
+}
+
+// TestDuplicateTablePolicies は重複テーブルの扱いごとに挿入されるレコードをテストする
+func TestDuplicateTablePolicies(t *testing.T) {
+	base := []byte(`
+users:
+  - id: 1
+    name: "山田太郎"
+  - id: 2
+    name: "田中花子"
+`)
+	override := []byte(`
+users:
+  - id: 2
+    name: "田中花子（更新）"
+  - id: 3
+    name: "鈴木一郎"
+`)
+
+	tests := []struct {
+		name   string
+		policy yamlfix.DuplicateTablePolicy
+		want   string
+	}{
+		{"replace", yamlfix.ReplaceDuplicateTables, "2:田中花子（更新）,3:鈴木一郎"},
+		{"append", yamlfix.AppendDuplicateTables, "1:山田太郎,2:田中花子,2:田中花子（更新）,3:鈴木一郎"},
+		{"merge by primary key", yamlfix.MergeDuplicateTablesByPrimaryKey, "1:山田太郎,2:田中花子（更新）,3:鈴木一郎"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			db.SetMaxOpenConns(1)
+
+			// 追加の場合は主キーが重複するため主キー制約を付けない
+			if _, err := db.Exec(`CREATE TABLE users (id INTEGER, name TEXT)`); err != nil {
+				t.Fatal(err)
+			}
+
+			fixture := yamlfix.New(yamlfix.Config{DB: db, DuplicateTables: tt.policy})
+			if err := fixture.LoadFromYAMLWithFilename(base, "base.yaml"); err != nil {
+				t.Fatal(err)
+			}
+			if err := fixture.LoadFromYAMLWithFilename(override, "override.yaml"); err != nil {
+				t.Fatal(err)
+			}
+			if err := fixture.InsertFixtures(); err != nil {
+				t.Fatal(err)
+			}
+
+			rows, err := db.Query(`SELECT id || ':' || name FROM users ORDER BY rowid`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+
+			var got []string
+			for rows.Next() {
+				var row string
+				if err := rows.Scan(&row); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, row)
+			}
+
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("expected: %s, got: %s", tt.want, strings.Join(got, ","))
+			}
+		})
+	}
+}
+
+// TestMergeByCompositePrimaryKey は Config.PrimaryKeys の複合主キーでレコードを照合することをテストする
+func TestMergeByCompositePrimaryKey(t *testing.T) {
+	fixture := yamlfix.New(yamlfix.Config{
+		DuplicateTables: yamlfix.MergeDuplicateTablesByPrimaryKey,
+		PrimaryKeys:     map[string][]string{"user_roles": {"user_id", "role_id"}},
+	})
+	if err := fixture.LoadFromYAMLWithFilename([]byte("- user_id: 1\n  role_id: 1\n"), "user_roles.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := fixture.LoadFromYAMLWithFilename([]byte("user_roles:\n  - user_id: 1\n"), "override.yaml")
+	if err == nil || !strings.Contains(err.Error(), "override.yaml:2") {
+		t.Errorf("expected missing primary key error with location, got: %v", err)
+	}
+}
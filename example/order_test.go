@@ -0,0 +1,130 @@
+package example
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestForeignKeyOrder は外部キーの参照先テーブルが先に挿入されることをテストする
+func TestForeignKeyOrder(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fixture := yamlfix.NewTestFixture(t, db)
+
+	// posts を users より先に記述しても参照先の users から挿入される
+	if err := fixture.LoadFromYAML([]byte(`
+posts:
+  - id: 1
+    user_id: 1
+    title: "最初の投稿"
+users:
+  - id: 1
+    name: "山田太郎"
+`)); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture.RunTestWithSetup(
+		func(tx *sql.Tx) {
+			_, err := tx.Exec(`
+				CREATE TABLE users (
+					id INTEGER PRIMARY KEY,
+					name TEXT NOT NULL
+				);
+				CREATE TABLE posts (
+					id INTEGER PRIMARY KEY,
+					user_id INTEGER NOT NULL REFERENCES users(id),
+					title TEXT NOT NULL
+				);
+			`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		},
+		func(tx *sql.Tx) {
+			var count int
+			if err := tx.QueryRow("SELECT COUNT(*) FROM posts").Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			if count != 1 {
+				t.Errorf("expected: 1, got: %d", count)
+			}
+		},
+	)
+}
+
+// TestForeignKeyCycle は循環参照の検出と制約遅延による挿入をテストする
+func TestForeignKeyCycle(t *testing.T) {
+	const schema = `
+		CREATE TABLE departments (
+			id INTEGER PRIMARY KEY,
+			manager_id INTEGER REFERENCES employees(id)
+		);
+		CREATE TABLE employees (
+			id INTEGER PRIMARY KEY,
+			department_id INTEGER REFERENCES departments(id)
+		);
+	`
+	const data = `
+departments:
+  - id: 1
+    manager_id: 1
+employees:
+  - id: 1
+    department_id: 1
+`
+
+	tests := map[string]struct {
+		deferConstraints bool
+		wantCycle        bool
+	}{
+		"循環参照をエラーとして報告": {deferConstraints: false, wantCycle: true},
+		"制約遅延により挿入":     {deferConstraints: true, wantCycle: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=on")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			db.SetMaxOpenConns(1) // メモリDBを単一コネクションで共有する
+
+			if _, err := db.Exec(schema); err != nil {
+				t.Fatal(err)
+			}
+
+			fixture := yamlfix.New(yamlfix.Config{
+				DB:               db,
+				AutoRollback:     true,
+				DeferConstraints: tt.deferConstraints,
+			})
+			if err := fixture.LoadFromYAML([]byte(data)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := fixture.BeginTransaction(); err != nil {
+				t.Fatal(err)
+			}
+			defer fixture.CleanUp()
+
+			err = fixture.InsertFixtures()
+			var cycleErr *yamlfix.CycleError
+			if got := errors.As(err, &cycleErr); got != tt.wantCycle {
+				t.Fatalf("InsertFixtures() error = %v, wantCycle %v", err, tt.wantCycle)
+			}
+			if !tt.wantCycle && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
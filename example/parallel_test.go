@@ -0,0 +1,70 @@
+package example
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestParallelSubtests はサブテストごとに独立したトランザクションで並列実行できることをテストする
+func TestParallelSubtests(t *testing.T) {
+	// 全セッションが同じDBを共有する。SQLiteは書き込みを直列化するため、トランザクションの開始時にロックを取って待ち合わせる
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "parallel.db")+"?_txlock=immediate&_busy_timeout=10000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	createUsersTable(tx, t)
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := yamlfix.NewTestFixture(t, db)
+	fixture.SetupTest("testdata/users.yaml")
+
+	repo := NewRepository()
+
+	// 並列のサブテストがすべて終わるまで待つためにグループ化する
+	t.Run("group", func(t *testing.T) {
+		for i := range 5 {
+			user := User{Name: fmt.Sprintf("並列ユーザー%d", i), Email: fmt.Sprintf("parallel%d@example.com", i)}
+
+			fixture.Run(t, fmt.Sprintf("ユーザー作成%d", i), func(t *testing.T, tx *sql.Tx) {
+				created, err := repo.CreateUser(t.Context(), tx, user)
+				if err != nil {
+					t.Fatalf("CreateUser() error = %v", err)
+				}
+
+				// 他のサブテストの挿入は見えず、フィクスチャの2件と作成した1件のみが存在する
+				var count int
+				if err := tx.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+					t.Fatal(err)
+				}
+				if count != 3 {
+					t.Errorf("expected: 3, got: %d", count)
+				}
+				if created.ID != 3 {
+					t.Errorf("ID - expected: 3, got: %d", created.ID)
+				}
+			})
+		}
+	})
+
+	// すべてのセッションがロールバックされ、共有DBには何も残らない
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected no rows after rollback, got: %d", count)
+	}
+}
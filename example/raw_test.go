@@ -0,0 +1,76 @@
+package example
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// rawFixture は !sql タグでSQL式を指定したフィクスチャ
+const rawFixture = `
+users:
+  - _label: yamada
+    id: 1
+    name: "山田太郎"
+    token: !sql lower(hex(randomblob(8)))
+    created_at: !sql CURRENT_TIMESTAMP
+  - id: 2
+    name: "田中花子"
+    token: "fixed"
+    created_at: !sql CURRENT_TIMESTAMP
+sessions:
+  - user_id: 1
+    token: $ref(users.yamada.token)
+`
+
+// TestRawSQL は !sql タグの値がSQL式として評価され、参照からも評価後の値が使われることをテストする
+func TestRawSQL(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, token TEXT, created_at TEXT);
+		CREATE TABLE sessions (user_id INTEGER, token TEXT);
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := yamlfix.New(yamlfix.Config{DB: db})
+	if err := fixture.LoadFromYAML([]byte(rawFixture)); err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.InsertFixtures(); err != nil {
+		t.Fatal(err)
+	}
+
+	var token, createdAt, sessionToken string
+	err = db.QueryRow(`SELECT u.token, u.created_at, s.token FROM users u JOIN sessions s ON s.user_id = u.id`).Scan(&token, &createdAt, &sessionToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 16 || token == "lower(hex(randomblob(8)))" {
+		t.Errorf("expected generated token, got: %s", token)
+	}
+	if createdAt == "" || createdAt == "CURRENT_TIMESTAMP" {
+		t.Errorf("expected current timestamp, got: %s", createdAt)
+	}
+	if sessionToken != token {
+		t.Errorf("expected session token %s, got: %s", token, sessionToken)
+	}
+}
+
+// TestDisableRawSQL は Config.DisableRawSQL を指定すると !sql タグがエラーになることをテストする
+func TestDisableRawSQL(t *testing.T) {
+	fixture := yamlfix.New(yamlfix.Config{DisableRawSQL: true})
+	err := fixture.LoadFromYAMLWithFilename([]byte(rawFixture), "raw.yaml")
+	if err == nil || !strings.Contains(err.Error(), "raw.yaml:3: column token") {
+		t.Errorf("expected raw SQL error with location, got: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package example
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestSymbolicReferences はラベルによるレコード間の参照が自動採番のIDに解決されることをテストする
+func TestSymbolicReferences(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fixture := yamlfix.NewTestFixture(t, db)
+	fixture.SetupTest("testdata/refs/blog.yaml")
+
+	fixture.RunTestWithSetup(
+		func(tx *sql.Tx) {
+			// 既存データにより自動採番が1から始まらない状態にする
+			_, err := tx.Exec(`
+				CREATE TABLE users (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT NOT NULL,
+					email TEXT NOT NULL,
+					invited_by INTEGER REFERENCES users(id)
+				);
+				CREATE TABLE posts (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					user_id INTEGER NOT NULL REFERENCES users(id),
+					title TEXT NOT NULL
+				);
+				INSERT INTO users (id, name, email) VALUES (100, '既存ユーザー', 'existing@example.com');
+			`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		},
+		func(tx *sql.Tx) {
+			rows, err := tx.Query(`
+				SELECT p.title, u.name, COALESCE(i.name, '')
+				FROM posts p
+				JOIN users u ON u.id = p.user_id
+				LEFT JOIN users i ON i.id = u.invited_by
+				ORDER BY p.id
+			`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+
+			var got []string
+			for rows.Next() {
+				var title, name, invitedBy string
+				if err := rows.Scan(&title, &name, &invitedBy); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, title+"/"+name+"/"+invitedBy)
+			}
+
+			want := "最初の投稿/山田太郎/,二番目の投稿/田中花子/山田太郎"
+			if strings.Join(got, ",") != want {
+				t.Errorf("expected: %s, got: %s", want, strings.Join(got, ","))
+			}
+		},
+	)
+}
+
+// TestUnknownReference は存在しないラベルへの参照がエラーになることをテストする
+func TestUnknownReference(t *testing.T) {
+	fixture := yamlfix.New(yamlfix.Config{})
+	err := fixture.LoadFromYAMLWithFilename([]byte(`
+users:
+  - _label: yamada
+    name: "山田太郎"
+posts:
+  - user_id: $ref(users.suzuki.id)
+`), "blog.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fixture.InsertFixtures()
+	if err == nil || !strings.Contains(err.Error(), "$ref(users.suzuki.id)") {
+		t.Errorf("expected unknown reference error, got: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package example
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestSequenceReset はIDを明示したフィクスチャの挿入後に作成したレコードのIDが重複しないことをテストする
+func TestSequenceReset(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fixture := yamlfix.NewTestFixture(t, db)
+	fixture.SetupTest("testdata/users.yaml")
+
+	fixture.RunTestWithSetup(
+		func(tx *sql.Tx) {
+			if _, err := tx.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, email TEXT, created_at TEXT)`); err != nil {
+				t.Fatal(err)
+			}
+		},
+		func(tx *sql.Tx) {
+			user, err := NewRepository().CreateUser(fixture.Context(), tx, User{Name: "鈴木一郎", Email: "suzuki@example.com"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.ID != 3 {
+				t.Errorf("expected id 3, got: %d", user.ID)
+			}
+		},
+	)
+}
+
+// recordingDialect は ResetSequence の呼び出しを記録する方言
+type recordingDialect struct {
+	yamlfix.SQLiteDialect
+	reset []string
+}
+
+// ResetSequence は呼び出されたテーブル名を記録する
+func (d *recordingDialect) ResetSequence(ctx context.Context, executor yamlfix.Executor, table string) error {
+	d.reset = append(d.reset, table)
+	return d.SQLiteDialect.ResetSequence(ctx, executor, table)
+}
+
+// TestDisableSequenceReset は Config.DisableSequenceReset を指定すると自動採番を進めないことをテストする
+func TestDisableSequenceReset(t *testing.T) {
+	for name, disable := range map[string]bool{"enabled": false, "disabled": true} {
+		t.Run(name, func(t *testing.T) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			db.SetMaxOpenConns(1)
+
+			if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT, created_at TEXT)`); err != nil {
+				t.Fatal(err)
+			}
+
+			dialect := &recordingDialect{}
+			fixture := yamlfix.New(yamlfix.Config{DB: db, Dialect: dialect, DisableSequenceReset: disable})
+			if err := fixture.LoadFromFile("testdata/users.yaml"); err != nil {
+				t.Fatal(err)
+			}
+			if err := fixture.InsertFixtures(); err != nil {
+				t.Fatal(err)
+			}
+
+			if disable && len(dialect.reset) != 0 {
+				t.Errorf("expected no sequence reset, got: %v", dialect.reset)
+			}
+			if !disable && (len(dialect.reset) != 1 || dialect.reset[0] != "users") {
+				t.Errorf("expected sequence reset of users, got: %v", dialect.reset)
+			}
+		})
+	}
+}
+
+// TestSQLiteResetSequence は sqlite_sequence を最大の rowid まで進めることをテストする
+func TestSQLiteResetSequence(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// SQLite は挿入時にも sqlite_sequence を進めるため、挿入後に値を戻してから確認する
+	_, err = db.Exec(`
+		CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT);
+		INSERT INTO users (id, name) VALUES (1, '山田太郎'), (5, '田中花子');
+		UPDATE sqlite_sequence SET seq = 1 WHERE name = 'users';
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (yamlfix.SQLiteDialect{}).ResetSequence(context.Background(), db, "users"); err != nil {
+		t.Fatal(err)
+	}
+
+	var seq int
+	if err := db.QueryRow(`SELECT seq FROM sqlite_sequence WHERE name = 'users'`).Scan(&seq); err != nil {
+		t.Fatal(err)
+	}
+	if seq != 5 {
+		t.Errorf("expected sequence 5, got: %d", seq)
+	}
+}
+
+// queryRecorder は実行したSQLを記録する Executor
+// 問い合わせは記録したうえで stub のクエリで代用し、更新系のSQLは実行しない
+type queryRecorder struct {
+	yamlfix.Executor
+	stub      string
+	queries   []string
+	queryArgs [][]interface{}
+	execs     []string
+	args      [][]interface{}
+}
+
+// QueryContext はSQLと引数を記録して stub のクエリの結果を返す
+func (r *queryRecorder) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	r.queries = append(r.queries, query)
+	r.queryArgs = append(r.queryArgs, args)
+	return r.Executor.QueryContext(ctx, r.stub)
+}
+
+// ExecContext は実行せずにSQLと引数を記録する
+func (r *queryRecorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.execs = append(r.execs, query)
+	r.args = append(r.args, args)
+	return driver.RowsAffected(0), nil
+}
+
+// TestPostgreSQLResetSequence は serial カラムのシーケンスを setval で進めるSQLを生成することをテストする
+func TestPostgreSQLResetSequence(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// serial カラムの問い合わせ結果として id を返す
+	recorder := &queryRecorder{Executor: db, stub: `SELECT 'id'`}
+	if err := (yamlfix.PostgreSQLDialect{}).ResetSequence(context.Background(), recorder, "public.users"); err != nil {
+		t.Fatal(err)
+	}
+
+	// serial カラムの問い合わせでは regclass と text に別々のパラメータを使う
+	wantQuery := `SELECT a.attname FROM pg_attribute a WHERE a.attrelid = $1::regclass AND a.attnum > 0 AND NOT a.attisdropped AND pg_get_serial_sequence($2, a.attname) IS NOT NULL`
+	if len(recorder.queries) != 1 || strings.Join(strings.Fields(recorder.queries[0]), " ") != wantQuery {
+		t.Fatalf("expected: %s, got: %v", wantQuery, recorder.queries)
+	}
+	if args := recorder.queryArgs[0]; len(args) != 2 || args[0] != `"public"."users"` || args[1] != `"public"."users"` {
+		t.Errorf("unexpected query args: %v", args)
+	}
+
+	want := `SELECT setval(pg_get_serial_sequence($1, $2), (SELECT COALESCE(MAX("id"), 0) + 1 FROM "public"."users"), false)`
+	if len(recorder.execs) != 1 || recorder.execs[0] != want {
+		t.Fatalf("expected: %s, got: %v", want, recorder.execs)
+	}
+	if args := recorder.args[0]; len(args) != 2 || args[0] != `"public"."users"` || args[1] != "id" {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
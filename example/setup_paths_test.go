@@ -0,0 +1,48 @@
+package example
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+)
+
+// TestSetupTestPaths は SetupTest がディレクトリとグロブを辞書順に展開して読み込むことをテストする
+func TestSetupTestPaths(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  []string
+	}{
+		{"directory", []string{"testdata/glob/master"}, []string{"roles", "tags"}},
+		{"glob", []string{"testdata/glob/*/*.yaml"}, []string{"tags", "users"}},
+		{"recursive glob", []string{"testdata/glob/**/*.yml"}, []string{"roles"}},
+		{"recursive directory", []string{"testdata/glob/**"}, []string{"roles", "tags", "users"}},
+		{"glob with non-YAML files", []string{"testdata/glob/*"}, []string{"roles", "tags", "users"}},
+		{"file and glob", []string{"testdata/glob/users/users.yaml", "testdata/glob/master/*"}, []string{"users", "roles", "tags"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := yamlfix.NewTestFixture(t, nil)
+			fixture.SetupTest(tt.paths...)
+
+			if got := fixture.Tables(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestLoadPatternNoMatch はパターンに一致するファイルがない場合に分かりやすいエラーになることをテストする
+func TestLoadPatternNoMatch(t *testing.T) {
+	for _, pattern := range []string{"testdata/glob/*.json", "testdata/glob/*.md", "testdata/missing/**/*.yaml"} {
+		fixture := yamlfix.New(yamlfix.Config{})
+		err := fixture.LoadFromFS(os.DirFS("."), pattern)
+		if err == nil || !strings.Contains(err.Error(), "no files match pattern "+pattern) {
+			t.Errorf("expected no match error for %s, got: %v", pattern, err)
+		}
+	}
+}
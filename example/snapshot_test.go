@@ -0,0 +1,108 @@
+package example
+
+import (
+	"database/sql"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	// go test ./example -update でスナップショットを書き換える
+	flag.BoolVar(&yamlfix.UpdateSnapshots, "update", false, "update snapshot files")
+}
+
+// TestSnapshot はテーブルの内容を testdata/snapshots のスナップショットと比較できることをテストする
+func TestSnapshot(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fixture := yamlfix.NewTestFixture(t, db)
+	fixture.SetupTest("testdata/users.yaml", "testdata/posts.yaml")
+
+	fixture.RunTestWithSetup(
+		func(tx *sql.Tx) { createBlogTables(t, tx) },
+		func(tx *sql.Tx) {
+			if _, err := tx.Exec(`UPDATE users SET name = '山田次郎' WHERE id = 1`); err != nil {
+				t.Fatal(err)
+			}
+
+			fixture.Snapshot(t, "after_update")
+			fixture.Snapshot(t, "users_only", yamlfix.OnlyTables("users"), yamlfix.IgnoreColumns("created_at"))
+
+			// -update の指定時はスナップショットを書き換えるため比較しない
+			if yamlfix.UpdateSnapshots {
+				return
+			}
+
+			// スナップショットと異なる内容は差分として報告される
+			if _, err := tx.Exec(`DELETE FROM posts WHERE id = 2`); err != nil {
+				t.Fatal(err)
+			}
+			rec := &recordingTB{TB: t}
+			fixture.Snapshot(rec, "after_update")
+			if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], `- id=2 (testdata/snapshots/TestSnapshot/after_update.yaml:`) {
+				t.Errorf("expected snapshot mismatch for posts, got: %v", rec.errors)
+			}
+		},
+	)
+}
+
+// TestLoadSnapshot はスナップショットをフィクスチャとして読み込めることをテストする
+func TestLoadSnapshot(t *testing.T) {
+	fixture := yamlfix.New(yamlfix.Config{})
+	if err := fixture.LoadFromFile("testdata/snapshots/TestSnapshot/after_update.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	tables := fixture.Tables()
+	if len(tables) != 2 || tables[0] != "users" || tables[1] != "posts" {
+		t.Errorf("expected users and posts, got: %v", tables)
+	}
+}
+
+// TestMissingSnapshot はスナップショットがない場合に書き出したうえでテストを失敗させることをテストする
+func TestMissingSnapshot(t *testing.T) {
+	if yamlfix.UpdateSnapshots {
+		t.Skip("snapshots are written without failing when -update is set")
+	}
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	t.Cleanup(func() { os.RemoveAll("testdata/snapshots/TestMissingSnapshot") })
+
+	fixture := yamlfix.NewTestFixture(t, db)
+	fixture.SetupTest("testdata/users.yaml")
+
+	fixture.RunTestWithSetup(
+		func(tx *sql.Tx) { createBlogTables(t, tx) },
+		func(tx *sql.Tx) {
+			rec := &recordingTB{TB: t}
+			fixture.Snapshot(rec, "missing")
+			if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "did not exist and has been written") {
+				t.Errorf("expected missing snapshot error, got: %v", rec.errors)
+			}
+			if _, err := os.Stat("testdata/snapshots/TestMissingSnapshot/missing.yaml"); err != nil {
+				t.Errorf("expected snapshot to be written: %v", err)
+			}
+
+			// 書き出したスナップショットとは一致する
+			rec = &recordingTB{TB: t}
+			fixture.Snapshot(rec, "missing")
+			if len(rec.errors) != 0 {
+				t.Errorf("expected written snapshot to match, got: %v", rec.errors)
+			}
+		},
+	)
+}
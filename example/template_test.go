@@ -0,0 +1,78 @@
+package example
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestTemplate はテンプレートの組み込み関数と FuncMap でレコードを生成できることをテストする
+func TestTemplate(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT, created_at TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := yamlfix.New(yamlfix.Config{
+		DB:       db,
+		Template: true,
+		FuncMap:  template.FuncMap{"domain": func() string { return "example.com" }},
+	})
+	if err := fixture.LoadFromFile("testdata/templates/users.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.InsertFixtures(); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 10 {
+		t.Errorf("expected 10 users, got %d", count)
+	}
+
+	var email, createdAt string
+	if err := db.QueryRow(`SELECT email, created_at FROM users WHERE id = 10`).Scan(&email, &createdAt); err != nil {
+		t.Fatal(err)
+	}
+	if email != "user10@example.com" {
+		t.Errorf("unexpected email: %s", email)
+	}
+	if want := time.Now().AddDate(0, 0, -3).Format("2006-01-02"); createdAt != want {
+		t.Errorf("expected created_at %s, got %s", want, createdAt)
+	}
+}
+
+// TestTemplateError はテンプレートのエラーにファイル名と行番号が含まれることをテストする
+func TestTemplateError(t *testing.T) {
+	fixture := yamlfix.New(yamlfix.Config{Template: true})
+	err := fixture.LoadFromYAMLWithFilename([]byte("users:\n  - id: 1\n    name: {{ unknown }}\n"), "users.yaml")
+	if err == nil {
+		t.Fatal("expected template error")
+	}
+	if !strings.Contains(err.Error(), "users.yaml:3") {
+		t.Errorf("expected error to contain file and line, got: %v", err)
+	}
+}
+
+// TestTemplateDisabled はテンプレートが無効の場合に {{ }} がそのまま値として扱われることをテストする
+func TestTemplateDisabled(t *testing.T) {
+	fixture := yamlfix.New(yamlfix.Config{})
+	err := fixture.LoadFromYAMLWithFilename([]byte("users:\n  - name: \"{{ now }}\"\n"), "users.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,121 @@
+package example
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestTruncateStrategy はフィクスチャがコミットされて別の接続から参照でき、
+// テスト終了時にテーブルが空になり自動採番がリセットされることをテストする
+func TestTruncateStrategy(t *testing.T) {
+	// 複数の接続から同じデータベースを参照するためファイルを使う
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "truncate.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`
+		CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, email TEXT, created_at TEXT);
+		CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER REFERENCES users(id), title TEXT, content TEXT, created_at TEXT);
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("committed", func(t *testing.T) {
+		tf := yamlfix.NewTestFixtureWithConfig(t, yamlfix.Config{DB: db, Strategy: yamlfix.TruncateStrategy})
+		tf.SetupTest("testdata/posts.yaml", "testdata/users.yaml")
+
+		tf.RunTest(func(tx *sql.Tx) {
+			if tx != nil {
+				t.Errorf("expected nil transaction with TruncateStrategy")
+			}
+
+			// テスト対象のコードが別の接続を使う場合もフィクスチャを参照できる
+			conn, err := db.Conn(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			var count int
+			if err := conn.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM posts").Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			if count != 2 {
+				t.Errorf("expected 2 posts, got: %d", count)
+			}
+		})
+	})
+
+	for _, table := range []string{"users", "posts"} {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("expected %s to be truncated, got %d rows", table, count)
+		}
+	}
+
+	result, err := db.Exec(`INSERT INTO users (name) VALUES ('新規ユーザー')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 {
+		t.Errorf("expected auto-increment to be reset to 1, got: %d", id)
+	}
+}
+
+// TestTruncateStrategyWithTransaction は BeginTransaction で開始したトランザクションを TearDownTest で終了し、
+// テーブルを空にできることをテストする
+func TestTruncateStrategyWithTransaction(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "truncate.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`
+		CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, email TEXT, created_at TEXT);
+		INSERT INTO users (id, name) VALUES (100, '既存ユーザー');
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &recordingTB{}
+	t.Run("manual", func(t *testing.T) {
+		rec.TB = t
+		tf := yamlfix.NewTestFixtureWithConfig(rec, yamlfix.Config{DB: db, Strategy: yamlfix.TruncateStrategy})
+		if err := tf.LoadFromFile("testdata/users.yaml"); err != nil {
+			t.Fatal(err)
+		}
+		if err := tf.BeginTransaction(); err != nil {
+			t.Fatal(err)
+		}
+		defer tf.TearDownTest()
+
+		tf.InsertTestData()
+	})
+
+	if len(rec.errors) != 0 {
+		t.Errorf("expected no errors, got: %v", rec.errors)
+	}
+
+	// 空にする処理がロールバックされず、コミット済みの行も削除される
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected users to be truncated, got %d rows", count)
+	}
+}
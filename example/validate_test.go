@@ -0,0 +1,144 @@
+package example
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Yuki-TU/yamlfix"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestValidate はスキーマと一致しないフィクスチャの問題が位置情報付きで報告されることをテストする
+func TestValidate(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			email TEXT NOT NULL,
+			age INTEGER,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := yamlfix.New(yamlfix.Config{DB: db})
+	err = fixture.LoadFromYAMLWithFilename([]byte(`
+users:
+  - name: "山田太郎"
+    emial: "yamada@example.com"
+  - name: "田中花子"
+    email: "tanaka@example.com"
+    age: "twenty"
+    created_at: 12345
+  - name: "鈴木一郎"
+    email: "suzuki@example.com"
+    age: 30
+    created_at: "2023-01-01 10:00:00"
+comments:
+  - id: 1
+`), "fixtures.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fixture.Validate(context.Background())
+	var validationErr *yamlfix.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError, got: %v", err)
+	}
+
+	want := []string{
+		"fixtures.yaml:3: unknown column emial in table users (did you mean email?)",
+		"fixtures.yaml:3: missing value for NOT NULL column email in table users",
+		"fixtures.yaml:5: value twenty of column age does not match type INTEGER in table users",
+		"fixtures.yaml:5: value 12345 of column created_at does not match type DATETIME in table users",
+		"fixtures.yaml:14: unknown table comments",
+	}
+	if strings.Join(validationErr.Problems, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected problems:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(validationErr.Problems, "\n"))
+	}
+}
+
+// TestValidateValidFixtures は正しいフィクスチャの検証が成功することをテストする
+func TestValidateValidFixtures(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT, created_at TEXT);
+		CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER, title TEXT, content TEXT, created_at TEXT);
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := yamlfix.New(yamlfix.Config{DB: db})
+	if err := fixture.LoadFromFile("testdata/multi_table.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.Validate(context.Background()); err != nil {
+		t.Errorf("expected no problems, got: %v", err)
+	}
+}
+
+// TestValidateNullMissingColumns は NullMissingColumns で NULL が挿入されるデフォルト値付きの NOT NULL カラムを報告することをテストする
+func TestValidateNullMissingColumns(t *testing.T) {
+	for name, policy := range map[string]yamlfix.MissingColumnPolicy{"omit": yamlfix.OmitMissingColumns, "null": yamlfix.NullMissingColumns} {
+		t.Run(name, func(t *testing.T) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			db.SetMaxOpenConns(1)
+
+			if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)`); err != nil {
+				t.Fatal(err)
+			}
+
+			fixture := yamlfix.New(yamlfix.Config{DB: db, MissingColumns: policy})
+			err = fixture.LoadFromYAMLWithFilename([]byte(`
+users:
+  - id: 1
+    name: "山田太郎"
+  - id: 2
+    name: "田中花子"
+    created_at: "2023-01-01 10:00:00"
+`), "users.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			validateErr := fixture.Validate(context.Background())
+			insertErr := fixture.InsertFixtures()
+			if policy == yamlfix.OmitMissingColumns {
+				if validateErr != nil || insertErr != nil {
+					t.Errorf("expected no problems, got: %v, %v", validateErr, insertErr)
+				}
+				return
+			}
+
+			want := "users.yaml:3: missing value for NOT NULL column created_at in table users is inserted as null by NullMissingColumns"
+			if validateErr == nil || !strings.Contains(validateErr.Error(), want) {
+				t.Errorf("expected problem %q, got: %v", want, validateErr)
+			}
+			if insertErr == nil {
+				t.Error("expected insert to fail")
+			}
+		})
+	}
+}
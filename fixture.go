@@ -1,11 +1,15 @@
 package yamlfix
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"path/filepath"
 	"strings"
+	"text/template"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -15,33 +19,142 @@ type Fixture struct {
 	db           *sql.DB
 	tx           *sql.Tx
 	tableOrder   []string
-	fixtures     map[string][]map[string]interface{}
+	fixtures     map[string][]*record
 	autoRollback bool
+	dialect      Dialect
+	deferCycles  bool
+
+	missingColumns MissingColumnPolicy
+	batchSize      int
+	useCopy        bool
+	template       bool
+	funcMap        template.FuncMap
+	fsys           fs.FS
+
+	duplicateTables DuplicateTablePolicy
+	primaryKeys     map[string][]string
+	sources         map[string][]string // テーブルごとの読み込み元ファイル
+	disableRawSQL   bool
+	valueConverter  ValueConverter
+	timeLocation    *time.Location
+
+	strategy             Strategy
+	insertedTables       []string // フィクスチャを挿入したテーブル（挿入順）
+	disableSequenceReset bool
 }
 
 // Config はFixtureの設定
 type Config struct {
 	DB           *sql.DB
-	AutoRollback bool // テスト後に自動でロールバックするかどうか
+	AutoRollback bool    // テスト後に自動でロールバックするかどうか
+	Dialect      Dialect // SQL方言（nilの場合はDBのドライバから自動判定）
+
+	// DeferConstraints は外部キーが循環しているテーブルを制約検査の遅延により挿入するかどうか
+	// 方言が ConstraintDeferrer を実装している必要があり、トランザクション内での使用を想定する
+	DeferConstraints bool
+
+	// MissingColumns はレコードによってカラム構成が異なる場合の欠けたカラムの扱い
+	MissingColumns MissingColumnPolicy
+
+	// BatchSize は1つのINSERT文にまとめる最大レコード数（0の場合は500、1の場合は1件ずつ挿入）
+	// 実際の件数は方言のパラメータ数上限に収まるよう調整される
+	BatchSize int
+
+	// UseCopy は方言が対応している場合に COPY FROM で挿入するかどうか（PostgreSQL + lib/pq）
+	UseCopy bool
+
+	// Template はYAMLを解析する前に text/template として評価するかどうか
+	// now, addDate, date, uuid, seq, env の組み込み関数と FuncMap の関数が使用できる
+	Template bool
+
+	// FuncMap はテンプレートで使用する関数を追加する（組み込み関数と同名の場合は上書きする）
+	FuncMap template.FuncMap
+
+	// FS はパスを指定する読み込み関数が使用するファイルシステム（nilの場合はOSのファイルシステム）
+	// embed.FS などを指定すると LoadFromFile や TestFixture.SetupTest もそこから読み込む
+	FS fs.FS
+
+	// DuplicateTables は同じテーブルが複数のファイルで定義された場合の扱い（デフォルトはエラー）
+	DuplicateTables DuplicateTablePolicy
+
+	// PrimaryKeys はテーブルごとの主キーのカラム（未指定のテーブルは id）
+	// DuplicateTables が MergeDuplicateTablesByPrimaryKey の場合にレコードの照合に使う
+	PrimaryKeys map[string][]string
+
+	// DisableRawSQL は !sql タグによるSQL式の埋め込みを禁止する（信頼できないフィクスチャを読み込む場合など）
+	DisableRawSQL bool
+
+	// ValueConverter は組み込みの変換と方言の変換より前に各カラムの値を変換する
+	ValueConverter ValueConverter
+
+	// TimeLocation は日時として解釈できる文字列を time.Time に変換する際のタイムゾーン
+	// nilの場合、日時はYAMLに記述した文字列のまま挿入する
+	TimeLocation *time.Location
+
+	// Strategy はテストで挿入したフィクスチャの後片付けの方法（デフォルトはトランザクションのロールバック）
+	Strategy Strategy
+
+	// DisableSequenceReset はテーブルの挿入後に自動採番の次の値を進める処理を無効にする
+	// 有効な場合、方言が SequenceResetter を実装していれば挿入したIDの最大値より先に進める
+	DisableSequenceReset bool
 }
 
+// MissingColumnPolicy はレコードに記述されていないカラムの扱いを表す
+type MissingColumnPolicy int
+
+const (
+	// OmitMissingColumns はレコードにないカラムをINSERTから省き、データベースのデフォルト値を適用する
+	OmitMissingColumns MissingColumnPolicy = iota
+	// NullMissingColumns はテーブル内の全レコードのカラムを揃え、レコードにないカラムには NULL を挿入する
+	NullMissingColumns
+)
+
 // New は新しいFixtureインスタンスを作成する
 func New(config Config) *Fixture {
+	dialect := config.Dialect
+	if dialect == nil {
+		dialect = DetectDialect(config.DB)
+	}
+
+	fsys := config.FS
+	if fsys == nil {
+		fsys = osFS{}
+	}
+
 	return &Fixture{
 		db:           config.DB,
-		fixtures:     make(map[string][]map[string]interface{}),
+		fixtures:     make(map[string][]*record),
 		autoRollback: config.AutoRollback,
+		dialect:      dialect,
+		deferCycles:  config.DeferConstraints,
+
+		missingColumns: config.MissingColumns,
+		batchSize:      config.BatchSize,
+		useCopy:        config.UseCopy,
+		template:       config.Template,
+		funcMap:        config.FuncMap,
+		fsys:           fsys,
+
+		duplicateTables: config.DuplicateTables,
+		primaryKeys:     config.PrimaryKeys,
+		sources:         make(map[string][]string),
+		disableRawSQL:   config.DisableRawSQL,
+		valueConverter:  config.ValueConverter,
+		timeLocation:    config.TimeLocation,
+
+		strategy:             config.Strategy,
+		disableSequenceReset: config.DisableSequenceReset,
 	}
 }
 
+// Dialect は使用中のSQL方言を返す
+func (f *Fixture) Dialect() Dialect {
+	return f.dialect
+}
+
 // LoadFromFile はYAMLファイルからフィクスチャを読み込む
 func (f *Fixture) LoadFromFile(filepath string) error {
-	data, err := os.ReadFile(filepath)
-	if err != nil {
-		return fmt.Errorf("failed to read YAML file: %w", err)
-	}
-
-	return f.LoadFromYAMLWithFilename(data, filepath)
+	return f.loadFile(f.fsys, filepath)
 }
 
 // LoadFromYAML はYAMLデータからフィクスチャを読み込む
@@ -50,35 +163,86 @@ func (f *Fixture) LoadFromYAML(data []byte) error {
 }
 
 // LoadFromYAMLWithFilename はYAMLデータをファイル名情報付きで読み込む
+// include / extends のファイルは Config.FS から読み込む
 func (f *Fixture) LoadFromYAMLWithFilename(data []byte, filename string) error {
+	return f.loadYAML(f.fsys, data, filename)
+}
+
+// loadYAML はYAMLデータを読み込む（include / extends のファイルは fsys から読み込む）
+func (f *Fixture) loadYAML(fsys fs.FS, data []byte, filename string) error {
+	tables, err := f.decodeFile(fsys, data, filename, nil)
+	if err != nil {
+		return err
+	}
+
+	if f.disableRawSQL {
+		if err := rejectRawSQL(tables); err != nil {
+			return err
+		}
+	}
+
+	return f.loadTables(tables, filename)
+}
+
+// decodeFile はYAMLデータをテーブル一覧に変換する
+// fsys は include / extends のファイルの読み込み元、stack は読み込み中のファイルで循環の検出に使う
+func (f *Fixture) decodeFile(fsys fs.FS, data []byte, filename string, stack []string) ([]tableData, error) {
+	// テンプレートを有効にしている場合は解析前に評価する
+	if f.template {
+		evaluated, err := f.evaluateTemplate(data, filename)
+		if err != nil {
+			return nil, err
+		}
+		data = evaluated
+	}
+
+	// テーブルやカラムの記述順を保持するためノードとして読み込む
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, &LoadError{SourceFile: filename, Line: yamlErrorLine(err), Err: fmt.Errorf("failed to parse YAML: %w", err)}
+	}
+
+	root := documentRoot(&doc)
+
 	// まず複数テーブル形式を試行
-	var multiTableData map[string][]map[string]interface{}
-	if err := yaml.Unmarshal(data, &multiTableData); err == nil {
-		// 複数テーブル形式として有効かチェック
-		if f.isMultiTableFormat(multiTableData) {
-			return f.loadMultiTableData(multiTableData)
+	if f.isMultiTableFormat(root) {
+		body, includes, err := splitIncludes(root, filename)
+		if err != nil {
+			return nil, err
+		}
+
+		// include / extends で読み込んだテーブルをこのファイルのレコードで上書きする
+		inherited, err := f.loadIncludes(fsys, includes, filename, stack)
+		if err != nil {
+			return nil, err
+		}
+
+		tables, err := decodeTables(body, filename)
+		if err != nil {
+			return nil, err
 		}
+		return f.overlayTables(inherited, tables)
 	}
 
 	// 単一テーブル形式を試行
-	var singleTableData []map[string]interface{}
-	if err := yaml.Unmarshal(data, &singleTableData); err != nil {
-		return fmt.Errorf("failed to parse YAML: %w", err)
+	records, defaults, err := decodeRecords(root, filename)
+	if err != nil {
+		return nil, err
 	}
 
 	// ファイル名からテーブル名を推測
 	tableName := f.extractTableNameFromFilename(filename)
 	if tableName == "" {
-		return fmt.Errorf("unable to determine table name: please specify filename or use multi-table format")
+		return nil, loadErrorf(filename, 0, "", "unable to determine table name: please specify filename or use multi-table format")
 	}
 
-	return f.loadSingleTableData(tableName, singleTableData)
+	return f.overlayTables(nil, []tableData{{name: tableName, records: records, defaults: defaults}})
 }
 
 // isMultiTableFormat は複数テーブル形式かどうかを判定する
-func (f *Fixture) isMultiTableFormat(data map[string][]map[string]interface{}) bool {
-	// 空でない場合は複数テーブル形式とみなす
-	return len(data) > 0
+func (f *Fixture) isMultiTableFormat(root *yaml.Node) bool {
+	// 空でないマッピングの場合は複数テーブル形式とみなす
+	return root != nil && root.Kind == yaml.MappingNode && len(root.Content) > 0
 }
 
 // extractTableNameFromFilename はファイル名からテーブル名を抽出する
@@ -93,73 +257,51 @@ func (f *Fixture) extractTableNameFromFilename(filename string) string {
 	return strings.TrimSuffix(base, ext)
 }
 
-// loadMultiTableData は複数テーブル形式のデータを読み込む
-func (f *Fixture) loadMultiTableData(yamlData map[string][]map[string]interface{}) error {
-	// 既存のデータにマージ
-	if f.fixtures == nil {
-		f.fixtures = make(map[string][]map[string]interface{})
-	}
-
-	for tableName, records := range yamlData {
-		f.fixtures[tableName] = records
+// loadTables は読み込んだテーブルを既存のデータにマージする
+func (f *Fixture) loadTables(tables []tableData, filename string) error {
+	for _, table := range tables {
+		if err := f.mergeTable(table.name, table.records, filename); err != nil {
+			return err
+		}
 	}
 
-	// テーブルの順序を更新
-	f.updateTableOrder()
 	return nil
 }
 
-// loadSingleTableData は単一テーブル形式のデータを読み込む
-func (f *Fixture) loadSingleTableData(tableName string, records []map[string]interface{}) error {
-	// 既存のデータにマージ
-	if f.fixtures == nil {
-		f.fixtures = make(map[string][]map[string]interface{})
-	}
-
-	f.fixtures[tableName] = records
-
-	// テーブルの順序を更新
-	f.updateTableOrder()
-	return nil
+// Tables は読み込み済みのテーブル名を読み込み順で返す
+func (f *Fixture) Tables() []string {
+	return append([]string(nil), f.tableOrder...)
 }
 
-// updateTableOrder はテーブルの順序を更新する
-func (f *Fixture) updateTableOrder() {
-	// 新しいテーブルを順序に追加
-	existingTables := make(map[string]bool)
-	for _, tableName := range f.tableOrder {
-		existingTables[tableName] = true
-	}
-
-	for tableName := range f.fixtures {
-		if !existingTables[tableName] {
-			f.tableOrder = append(f.tableOrder, tableName)
+// updateTableOrder は未登録のテーブルを読み込み順で順序に追加する
+func (f *Fixture) updateTableOrder(tableName string) {
+	for _, existing := range f.tableOrder {
+		if existing == tableName {
+			return
 		}
 	}
+
+	f.tableOrder = append(f.tableOrder, tableName)
 }
 
 // LoadFromDirectory は指定ディレクトリ内の全YAMLファイルを読み込む
 func (f *Fixture) LoadFromDirectory(dirPath string) error {
-	return filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if !info.IsDir() && (strings.HasSuffix(path, ".yml") || strings.HasSuffix(path, ".yaml")) {
-			return f.LoadFromFile(path)
-		}
-
-		return nil
-	})
+	return f.loadDirectory(f.fsys, dirPath)
 }
 
 // BeginTransaction はトランザクションを開始する
 func (f *Fixture) BeginTransaction() error {
+	return f.BeginTx(context.Background(), nil)
+}
+
+// BeginTx はコンテキストとオプションを指定してトランザクションを開始する
+// ctx がキャンセルされるとトランザクションはロールバックされる
+func (f *Fixture) BeginTx(ctx context.Context, opts *sql.TxOptions) error {
 	if f.tx != nil {
 		return fmt.Errorf("transaction already started")
 	}
 
-	tx, err := f.db.Begin()
+	tx, err := f.db.BeginTx(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("failed to start transaction: %w", err)
 	}
@@ -192,16 +334,57 @@ func (f *Fixture) RollbackTransaction() error {
 
 // InsertFixtures はフィクスチャデータをデータベースに挿入する
 func (f *Fixture) InsertFixtures() error {
+	return f.InsertFixturesContext(context.Background())
+}
+
+// InsertFixturesContext はコンテキストを指定してフィクスチャデータをデータベースに挿入する
+func (f *Fixture) InsertFixturesContext(ctx context.Context) (err error) {
 	executor := f.getExecutor()
 
-	for _, tableName := range f.tableOrder {
+	// ラベルと参照を検証する
+	refs, err := f.newRefResolver()
+	if err != nil {
+		return err
+	}
+
+	// 外部キーや $ref の参照先テーブルが先に挿入されるよう並べ替える
+	order, err := f.sortTables(ctx, executor)
+	if err != nil {
+		var cycleErr *CycleError
+		if !errors.As(err, &cycleErr) || !f.deferCycles {
+			return err
+		}
+
+		deferrer, ok := f.dialect.(ConstraintDeferrer)
+		if !ok {
+			return fmt.Errorf("%w: dialect %s does not support deferred constraints", err, f.dialect.Name())
+		}
+
+		restore, deferErr := deferrer.DeferConstraints(ctx, executor)
+		if deferErr != nil {
+			return fmt.Errorf("failed to defer constraints: %w", deferErr)
+		}
+		defer func() {
+			if restoreErr := restore(); restoreErr != nil && err == nil {
+				err = fmt.Errorf("failed to restore constraints: %w", restoreErr)
+			}
+		}()
+	}
+
+	for _, tableName := range order {
 		records := f.fixtures[tableName]
 		if len(records) == 0 {
 			continue
 		}
 
-		if err := f.insertTable(executor, tableName, records); err != nil {
-			return fmt.Errorf("failed to insert into table %s: %w", tableName, err)
+		if err := f.insertTable(ctx, executor, refs, tableName, records); err != nil {
+			return err
+		}
+		f.markInserted(tableName)
+
+		// IDを明示して挿入しても、後から挿入されるレコードのIDが重複しないようにする
+		if err := f.resetSequence(ctx, executor, tableName); err != nil {
+			return err
 		}
 	}
 
@@ -209,16 +392,28 @@ func (f *Fixture) InsertFixtures() error {
 }
 
 // CleanUp はフィクスチャのクリーンアップを行う
+// AutoRollback の場合は開いているトランザクションをロールバックし、TruncateStrategy の場合は挿入したテーブルを空にする
 func (f *Fixture) CleanUp() error {
+	// ロールバックされるトランザクション内で空にしないよう、先にトランザクションを終了する
 	if f.autoRollback && f.tx != nil {
-		return f.RollbackTransaction()
+		if err := f.RollbackTransaction(); err != nil {
+			return err
+		}
+	}
+	if f.strategy == TruncateStrategy {
+		return f.TruncateFixtures(context.Background())
 	}
 	return nil
 }
 
 // WithTransaction はトランザクション内でコールバック関数を実行する
 func (f *Fixture) WithTransaction(fn func() error) error {
-	if err := f.BeginTransaction(); err != nil {
+	return f.WithTransactionContext(context.Background(), fn)
+}
+
+// WithTransactionContext はコンテキストを指定してトランザクション内でコールバック関数を実行する
+func (f *Fixture) WithTransactionContext(ctx context.Context, fn func() error) error {
+	if err := f.BeginTx(ctx, nil); err != nil {
 		return err
 	}
 
@@ -228,7 +423,7 @@ func (f *Fixture) WithTransaction(fn func() error) error {
 		}
 	}()
 
-	if err := f.InsertFixtures(); err != nil {
+	if err := f.InsertFixturesContext(ctx); err != nil {
 		f.RollbackTransaction()
 		return err
 	}
@@ -245,13 +440,19 @@ func (f *Fixture) WithTransaction(fn func() error) error {
 	return nil
 }
 
-// Executor はSQL実行用のインターフェース
+// Executor はSQL実行用のインターフェース（*sql.DB と *sql.Tx が実装する）
 type Executor interface {
-	Exec(query string, args ...interface{}) (sql.Result, error)
-	Query(query string, args ...interface{}) (*sql.Rows, error)
-	QueryRow(query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
 }
 
+var (
+	_ Executor = (*sql.DB)(nil)
+	_ Executor = (*sql.Tx)(nil)
+)
+
 // getExecutor は実行用のインターフェースを取得する
 func (f *Fixture) getExecutor() Executor {
 	if f.tx != nil {
@@ -259,42 +460,3 @@ func (f *Fixture) getExecutor() Executor {
 	}
 	return f.db
 }
-
-// insertTable は指定テーブルにレコードを挿入する
-func (f *Fixture) insertTable(executor Executor, tableName string, records []map[string]interface{}) error {
-
-	if len(records) == 0 {
-		return nil
-	}
-
-	// カラム名を取得
-	columns := make([]string, 0)
-	for col := range records[0] {
-		columns = append(columns, col)
-	}
-
-	// プレースホルダーを作成
-	placeholders := make([]string, len(columns))
-	for i := range placeholders {
-		placeholders[i] = "?"
-	}
-
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		tableName,
-		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "))
-
-	// レコードを順次挿入
-	for _, record := range records {
-		values := make([]interface{}, len(columns))
-		for i, col := range columns {
-			values[i] = record[col]
-		}
-
-		if _, err := executor.Exec(query, values...); err != nil {
-			return fmt.Errorf("failed to insert record: %w", err)
-		}
-	}
-
-	return nil
-}
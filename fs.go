@@ -0,0 +1,217 @@
+package yamlfix
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// osFS は Config.FS が未指定の場合に使うOSのファイルシステム
+// os.DirFS と異なり、絶対パスや ".." を含む相対パスもそのまま扱える
+type osFS struct{}
+
+var (
+	_ fs.ReadFileFS = osFS{}
+	_ fs.ReadDirFS  = osFS{}
+	_ fs.StatFS     = osFS{}
+	_ fs.GlobFS     = osFS{}
+)
+
+func (osFS) Open(name string) (fs.File, error)          { return os.Open(name) }
+func (osFS) ReadFile(name string) ([]byte, error)       { return os.ReadFile(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (osFS) Glob(pattern string) ([]string, error)      { return filepath.Glob(pattern) }
+
+// LoadFromFS は fs.FS からパターンに一致するフィクスチャを読み込む（embed.FS や fstest.MapFS など）
+// パターンの書式は TestFixture.SetupTest と同じで、ファイル・ディレクトリ・グロブ（** を含む）を指定できる
+func (f *Fixture) LoadFromFS(fsys fs.FS, patterns ...string) error {
+	return f.loadPatterns(fsys, patterns)
+}
+
+// loadPatterns はパターンごとに一致したパスを辞書順に読み込む
+func (f *Fixture) loadPatterns(fsys fs.FS, patterns []string) error {
+	for _, pattern := range patterns {
+		// グロブでないパスは存在しない場合のエラーをそのまま返す
+		if !hasMeta(pattern) {
+			if err := f.loadPath(fsys, pattern); err != nil {
+				return err
+			}
+			continue
+		}
+
+		matches, err := globFS(fsys, pattern)
+		if err != nil {
+			return fmt.Errorf("failed to match pattern %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("no files match pattern %s", pattern)
+		}
+
+		for _, path := range matches {
+			if err := f.loadPath(fsys, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// globFS はパターンに一致するYAMLファイルとディレクトリのパスを辞書順で返す（その他のファイルは除外する）
+// ** を含むパターンは0個以上のディレクトリに一致し、配下のYAMLファイルのみを返す
+func globFS(fsys fs.FS, pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		filtered := matches[:0]
+		for _, match := range matches {
+			if isYAMLFile(match) {
+				filtered = append(filtered, match)
+				continue
+			}
+			info, err := fs.Stat(fsys, match)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				filtered = append(filtered, match)
+			}
+		}
+		sort.Strings(filtered)
+		return filtered, nil
+	}
+
+	// メタ文字を含まない先頭部分を起点に走査する
+	segments := strings.Split(pattern, "/")
+	root := 0
+	for root < len(segments)-1 && !hasMeta(segments[root]) {
+		root++
+	}
+	dir := strings.Join(segments[:root], "/")
+	switch {
+	case root == 0:
+		dir = "."
+	case dir == "":
+		dir = "/"
+	}
+
+	// 起点が存在しない場合は一致なしとする
+	if _, err := fs.Stat(fsys, dir); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var matches []string
+	err := fs.WalkDir(fsys, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isYAMLFile(path) {
+			return nil
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(path, dir), "/")
+		if dir == "." {
+			rel = path
+		}
+		ok, err := matchSegments(segments[root:], strings.Split(rel, "/"))
+		if err != nil {
+			return err
+		}
+		if ok {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// matchSegments はパス要素ごとにパターンを照合する（** は0個以上の要素に一致する）
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				ok, err := matchSegments(pattern[1:], name[i:])
+				if ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if !ok || err != nil {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0, nil
+}
+
+// hasMeta はパス要素がグロブのメタ文字を含むかどうかを判定する
+func hasMeta(segment string) bool {
+	return strings.ContainsAny(segment, `*?[\`)
+}
+
+// loadPath はファイルならそのまま、ディレクトリなら配下のYAMLファイルを読み込む
+func (f *Fixture) loadPath(fsys fs.FS, path string) error {
+	info, err := fs.Stat(fsys, path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	if info.IsDir() {
+		return f.loadDirectory(fsys, path)
+	}
+	return f.loadFile(fsys, path)
+}
+
+// loadFile は fs.FS からYAMLファイルを1つ読み込む
+func (f *Fixture) loadFile(fsys fs.FS, path string) error {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return &LoadError{SourceFile: path, Err: fmt.Errorf("failed to read YAML file: %w", err)}
+	}
+
+	return f.loadYAML(fsys, data, path)
+}
+
+// loadDirectory は fs.FS のディレクトリ配下のYAMLファイルをパス順に読み込む
+func (f *Fixture) loadDirectory(fsys fs.FS, dirPath string) error {
+	return fs.WalkDir(fsys, dirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && isYAMLFile(path) {
+			return f.loadFile(fsys, path)
+		}
+
+		return nil
+	})
+}
+
+// isYAMLFile はYAMLファイルの拡張子かどうかを判定する
+func isYAMLFile(path string) bool {
+	return strings.HasSuffix(path, ".yml") || strings.HasSuffix(path, ".yaml")
+}
@@ -0,0 +1,213 @@
+package yamlfix
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// includeKey は他のフィクスチャファイルを取り込む最上位のキー（文字列またはリスト）
+	includeKey = "include"
+	// extendsKey は継承元のフィクスチャファイルを指定する最上位のキー
+	extendsKey = "extends"
+	// deleteKey は継承したレコードを削除する指定
+	deleteKey = "_delete"
+)
+
+// splitIncludes は複数テーブル形式の最上位から include と extends を取り除き、取り込むファイルを返す
+// extends のファイルを先に、include のファイルを記述順に返す
+func splitIncludes(root *yaml.Node, filename string) (*yaml.Node, []string, error) {
+	var extends, includes []string
+	body := *root
+	body.Content = make([]*yaml.Node, 0, len(root.Content))
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		keyNode, valueNode := root.Content[i], root.Content[i+1]
+
+		switch keyNode.Value {
+		case extendsKey:
+			if valueNode.Kind != yaml.ScalarNode || valueNode.Value == "" {
+				return nil, nil, loadErrorf(filename, valueNode.Line, "", "%s must be a file path", extendsKey)
+			}
+			extends = append(extends, valueNode.Value)
+		case includeKey:
+			paths, err := decodePaths(valueNode, filename)
+			if err != nil {
+				return nil, nil, err
+			}
+			includes = append(includes, paths...)
+		default:
+			body.Content = append(body.Content, keyNode, valueNode)
+		}
+	}
+
+	return &body, append(extends, includes...), nil
+}
+
+// decodePaths は文字列または文字列のリストのノードをパスの一覧に変換する
+func decodePaths(node *yaml.Node, filename string) ([]string, error) {
+	var paths []string
+	switch node.Kind {
+	case yaml.ScalarNode:
+		paths = []string{node.Value}
+	case yaml.SequenceNode:
+		if err := node.Decode(&paths); err != nil {
+			return nil, loadErrorf(filename, node.Line, "", "%s must be a list of file paths", includeKey)
+		}
+	default:
+		return nil, loadErrorf(filename, node.Line, "", "%s must be a file path or a list of file paths", includeKey)
+	}
+	return paths, nil
+}
+
+// loadIncludes は取り込むファイルを順に読み込み、後のファイルのレコードで前のファイルのレコードを上書きする
+func (f *Fixture) loadIncludes(fsys fs.FS, includes []string, filename string, stack []string) ([]tableData, error) {
+	if len(includes) == 0 {
+		return nil, nil
+	}
+
+	if filename != "" {
+		stack = append(stack[:len(stack):len(stack)], path.Clean(filename))
+	}
+
+	var inherited []tableData
+	for _, include := range includes {
+		// 相対パスは取り込み元のファイルを基準に解決する
+		resolved := include
+		if !path.IsAbs(include) {
+			resolved = path.Join(path.Dir(filename), include)
+		}
+
+		if containsString(stack, resolved) {
+			return nil, loadErrorf(filename, 0, "", "include cycle detected: %s", strings.Join(append(stack, resolved), " -> "))
+		}
+
+		data, err := fs.ReadFile(fsys, resolved)
+		if err != nil {
+			return nil, loadErrorf(filename, 0, "", "failed to include %s: %w", include, err)
+		}
+
+		tables, err := f.decodeFile(fsys, data, resolved, stack)
+		if err != nil {
+			return nil, fmt.Errorf("failed to include %s from %s: %w", include, displayName(filename), err)
+		}
+
+		inherited, err = f.overlayTables(inherited, tables)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return inherited, nil
+}
+
+// overlayTables は継承したテーブルに上書きするテーブルのレコードを重ねる
+// 同じテーブルのレコードはラベルまたは主キーで照合し、一致したレコードはカラム単位で上書きする
+// デフォルト値は追加したレコードにのみ適用し、上書きしたレコードでは継承した値を優先する
+func (f *Fixture) overlayTables(base, overrides []tableData) ([]tableData, error) {
+	tables := append([]tableData(nil), base...)
+	index := make(map[string]int, len(tables))
+	for i, table := range tables {
+		index[table.name] = i
+	}
+
+	for _, table := range overrides {
+		i, ok := index[table.name]
+		if !ok {
+			index[table.name] = len(tables)
+			tables = append(tables, tableData{name: table.name})
+			i = len(tables) - 1
+		}
+
+		records, added, err := f.overrideRecords(table.name, tables[i].records, table.records)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.applyDefaults([]tableData{{name: table.name, records: added, defaults: table.defaults}}); err != nil {
+			return nil, err
+		}
+		tables[i].records = records
+	}
+
+	return tables, nil
+}
+
+// overrideRecords は継承したレコードを上書き・削除し、一致しないレコードを末尾に追加する
+// 照合するのは継承したレコードのみで、overrides 同士はキーが同じでも別のレコードとして扱う
+// 戻り値の added は末尾に追加したレコード
+func (f *Fixture) overrideRecords(tableName string, base, overrides []*record) (records, added []*record, err error) {
+	records = append([]*record(nil), base...)
+	inherited := len(records) // records[:inherited] が継承したレコード
+
+	for _, rec := range overrides {
+		i := f.findRecord(tableName, records[:inherited], rec)
+		switch {
+		case i < 0 && rec.deleted:
+			return nil, nil, loadErrorf(rec.source, rec.line, "", "record to delete does not match any included record in table %s", tableName)
+		case i < 0:
+			records = append(records, rec)
+			added = append(added, rec)
+		case rec.deleted:
+			records = append(records[:i], records[i+1:]...)
+			inherited--
+		default:
+			records[i] = mergeRecord(records[i], rec)
+		}
+	}
+
+	return records, added, nil
+}
+
+// findRecord はラベルまたは主キーが一致するレコードの位置を返す（見つからない場合は -1）
+func (f *Fixture) findRecord(tableName string, records []*record, rec *record) int {
+	if rec.label != "" {
+		for i, candidate := range records {
+			if candidate.label == rec.label {
+				return i
+			}
+		}
+		return -1
+	}
+
+	primaryKey := f.primaryKey(tableName)
+	key, err := primaryKeyOf(rec, primaryKey)
+	if err != nil {
+		return -1
+	}
+	for i, candidate := range records {
+		if candidateKey, err := primaryKeyOf(candidate, primaryKey); err == nil && candidateKey == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mergeRecord は継承したレコードのカラムを上書きしたレコードを作成する
+func mergeRecord(base, override *record) *record {
+	merged := &record{
+		columns: append([]string(nil), base.columns...),
+		values:  make(map[string]interface{}, len(base.values)+len(override.values)),
+		label:   base.label,
+		source:  override.source,
+		line:    override.line,
+	}
+	for col, value := range base.values {
+		merged.values[col] = value
+	}
+
+	for _, col := range override.columns {
+		if _, ok := merged.values[col]; !ok {
+			merged.columns = append(merged.columns, col)
+		}
+		merged.values[col] = override.values[col]
+	}
+	if override.label != "" {
+		merged.label = override.label
+	}
+
+	return merged
+}
@@ -0,0 +1,443 @@
+package yamlfix
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// defaultBatchSize は Config.BatchSize が未指定の場合に1つのINSERT文にまとめる最大レコード数
+const defaultBatchSize = 500
+
+// BatchLimiter は複数行のINSERT文に対応する方言が実装するインターフェース
+// 実装していない方言では1件ずつ挿入する
+type BatchLimiter interface {
+	// BatchLimits は1文あたりのバインドパラメータ数と行数の上限を返す（0は上限なし）
+	BatchLimits() (maxParams, maxRows int)
+}
+
+// CopyInserter は COPY FROM による一括挿入に対応する方言が実装するインターフェース
+type CopyInserter interface {
+	// CopyFromQuery は tx.Prepare に渡す COPY 文を返す
+	CopyFromQuery(tableName string, columns []string) string
+}
+
+// BatchLimits はSQLiteのパラメータ数上限を返す
+func (SQLiteDialect) BatchLimits() (int, int) { return 32766, 0 }
+
+// BatchLimits はMySQLのパラメータ数上限を返す
+func (MySQLDialect) BatchLimits() (int, int) { return 65535, 0 }
+
+// BatchLimits はPostgreSQLのパラメータ数上限を返す
+func (PostgreSQLDialect) BatchLimits() (int, int) { return 65535, 0 }
+
+// BatchLimits はSQL Serverのパラメータ数上限（2100、内部利用分を除く）と VALUES 句の行数上限を返す
+func (SQLServerDialect) BatchLimits() (int, int) { return 2000, 1000 }
+
+// CopyFromQuery は lib/pq の CopyIn と同じ形式の COPY 文を返す
+func (d PostgreSQLDialect) CopyFromQuery(tableName string, columns []string) string {
+	return fmt.Sprintf("COPY %s (%s) FROM STDIN", d.QuoteIdentifier(tableName), quoteColumns(d, columns))
+}
+
+// insertTable は指定テーブルにレコードを挿入する
+func (f *Fixture) insertTable(ctx context.Context, executor Executor, refs *refResolver, tableName string, records []*record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	// レコードごとのカラム一覧を決定する
+	columnsOf := func(rec *record) []string { return rec.columns }
+	if f.missingColumns == NullMissingColumns {
+		union := unionColumns(records)
+		columnsOf = func(*record) []string { return union }
+	}
+
+	// 同じクエリはプリペアドステートメントを共有する
+	stmts := newStmtCache(executor)
+	defer stmts.close()
+
+	// 記述順を保つため、同じカラム構成が連続する範囲ごとにまとめて挿入する
+	for start := 0; start < len(records); {
+		columns := columnsOf(records[start])
+
+		// 参照の解決に挿入結果が必要なレコードは1件ずつ挿入する
+		if refs.isolated(tableName, records[start]) {
+			if err := f.insertIsolated(ctx, stmts, refs, tableName, columns, start, records[start]); err != nil {
+				return err
+			}
+			start++
+			continue
+		}
+
+		end := start + 1
+		for end < len(records) && equalColumns(columnsOf(records[end]), columns) && !refs.isolated(tableName, records[end]) {
+			end++
+		}
+
+		if err := f.insertRun(ctx, stmts, refs, tableName, columns, start, records[start:end]); err != nil {
+			return err
+		}
+		start = end
+	}
+
+	return nil
+}
+
+// insertRun は同じカラム構成のレコード群を挿入する（offset は先頭レコードのテーブル内の位置）
+func (f *Fixture) insertRun(ctx context.Context, stmts *stmtCache, refs *refResolver, tableName string, columns []string, offset int, records []*record) error {
+	// 生のSQL式は COPY で送れないため通常のINSERT文で挿入する
+	if copier, ok := f.dialect.(CopyInserter); ok && f.useCopy && !containsRawSQL(records) {
+		return f.copyRun(ctx, stmts, refs, copier, tableName, columns, offset, records)
+	}
+
+	batchRows := f.batchRows(len(columns))
+	for start := 0; start < len(records); start += batchRows {
+		batch := records[start:min(start+batchRows, len(records))]
+
+		resolved := make([][]interface{}, len(batch))
+		tuples := make([][]string, len(batch))
+		args := make([]interface{}, 0, len(batch)*len(columns))
+		for i, rec := range batch {
+			values, err := f.resolveValues(refs, tableName, offset+start+i, rec, columns)
+			if err != nil {
+				return err
+			}
+			resolved[i] = values
+
+			placeholders, rowArgs := f.bindValues(values, len(args)+1)
+			tuples[i] = placeholders
+			args = append(args, rowArgs...)
+		}
+
+		query := f.buildInsertQuery(tableName, columns, tuples)
+		if err := f.execBatch(ctx, stmts, tableName, columns, offset+start, batch, resolved, query, args); err != nil {
+			return err
+		}
+
+		for i, rec := range batch {
+			refs.store(rec, columns, resolved[i], nil)
+		}
+	}
+
+	return nil
+}
+
+// batchSavepoint は複数行のINSERT文の失敗時に挿入前の状態へ戻すためのセーブポイント名
+const batchSavepoint = "yamlfix_batch"
+
+// execBatch は複数行のINSERT文を実行する（index は先頭レコードのテーブル内の位置）
+// 失敗した場合は1件ずつ挿入し直して原因のレコードを特定し、特定できなければ先頭のレコードと行の範囲を報告する
+func (f *Fixture) execBatch(ctx context.Context, stmts *stmtCache, tableName string, columns []string, index int, batch []*record, resolved [][]interface{}, query string, args []interface{}) error {
+	// PostgreSQL では失敗した文の後はトランザクションが中断されるため、挿入前にセーブポイントを作る
+	tx, inTx := stmts.executor.(*sql.Tx)
+	useSavepoint := inTx && len(batch) > 1
+	create, rollback, release := f.savepointSQL(batchSavepoint)
+	if useSavepoint {
+		if _, err := tx.ExecContext(ctx, create); err != nil {
+			return fmt.Errorf("failed to create savepoint %s: %w", batchSavepoint, err)
+		}
+	}
+
+	stmt, err := stmts.prepare(ctx, query)
+	if err == nil {
+		_, err = stmt.ExecContext(ctx, args...)
+	}
+	if err == nil {
+		if useSavepoint && release != "" {
+			if _, err := tx.ExecContext(ctx, release); err != nil {
+				return fmt.Errorf("failed to release savepoint %s: %w", batchSavepoint, err)
+			}
+		}
+		return nil
+	}
+
+	batchErr := newInsertError(tableName, index, batch[0], err)
+	batchErr.Query, batchErr.Args = query, args
+	if len(batch) == 1 {
+		return batchErr
+	}
+	batchErr.location = batchLocation(batch)
+
+	var rowErr *InsertError
+	switch {
+	case useSavepoint:
+		// 1件ずつ挿入し直した結果も取り消し、トランザクションを挿入前の状態に戻す
+		if _, err := tx.ExecContext(ctx, rollback); err != nil {
+			return batchErr
+		}
+		rowErr = f.findFailedRecord(ctx, tx, tableName, columns, index, batch, resolved)
+		if _, err := tx.ExecContext(ctx, rollback); err != nil {
+			return batchErr
+		}
+		if release != "" {
+			tx.ExecContext(ctx, release)
+		}
+	default:
+		// トランザクション外ではコミットされないよう、別のトランザクションで挿入し直してロールバックする
+		beginner, ok := stmts.executor.(interface {
+			BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+		})
+		if !ok {
+			return batchErr
+		}
+		retryTx, err := beginner.BeginTx(ctx, nil)
+		if err != nil {
+			return batchErr
+		}
+		rowErr = f.findFailedRecord(ctx, retryTx, tableName, columns, index, batch, resolved)
+		retryTx.Rollback()
+	}
+
+	if rowErr != nil {
+		return rowErr
+	}
+	return batchErr
+}
+
+// findFailedRecord はレコードを1件ずつ挿入し、最初に失敗したレコードのエラーを返す（すべて成功した場合は nil）
+func (f *Fixture) findFailedRecord(ctx context.Context, executor Executor, tableName string, columns []string, index int, batch []*record, resolved [][]interface{}) *InsertError {
+	for i, rec := range batch {
+		placeholders, args := f.bindValues(resolved[i], 1)
+		query := f.buildInsertQuery(tableName, columns, [][]string{placeholders})
+		if _, err := executor.ExecContext(ctx, query, args...); err != nil {
+			insertErr := newInsertError(tableName, index+i, rec, err)
+			insertErr.Query, insertErr.Args = query, args
+			return insertErr
+		}
+	}
+	return nil
+}
+
+// insertIsolated は1件のレコードを挿入し、参照に必要な生成値を取得する（index はテーブル内の位置）
+func (f *Fixture) insertIsolated(ctx context.Context, stmts *stmtCache, refs *refResolver, tableName string, columns []string, index int, rec *record) error {
+	values, err := f.resolveValues(refs, tableName, index, rec, columns)
+	if err != nil {
+		return err
+	}
+	placeholders, args := f.bindValues(values, 1)
+
+	var query string
+	var returned map[string]interface{}
+	if returning := refs.returning[rec]; len(returning) > 0 {
+		query, returned, err = f.insertReturning(ctx, stmts.executor, tableName, columns, placeholders, args, returning)
+	} else {
+		var stmt *sql.Stmt
+		query = f.buildInsertQuery(tableName, columns, [][]string{placeholders})
+		stmt, err = stmts.prepare(ctx, query)
+		if err == nil {
+			_, err = stmt.ExecContext(ctx, args...)
+		}
+	}
+	if err != nil {
+		insertErr := newInsertError(tableName, index, rec, err)
+		insertErr.Query, insertErr.Args = query, args
+		return insertErr
+	}
+
+	refs.store(rec, columns, values, returned)
+	return nil
+}
+
+// copyRun は COPY FROM でレコード群を挿入する
+func (f *Fixture) copyRun(ctx context.Context, stmts *stmtCache, refs *refResolver, copier CopyInserter, tableName string, columns []string, offset int, records []*record) error {
+	// COPY 文は行の送信後に引数なしの Exec で完了させるため、キャッシュせず使い捨てる
+	query := copier.CopyFromQuery(tableName, columns)
+	stmt, err := stmts.executor.PrepareContext(ctx, query)
+	if err != nil {
+		insertErr := newInsertError(tableName, offset, records[0], err)
+		insertErr.Query = query
+		if len(records) > 1 {
+			insertErr.location = batchLocation(records)
+		}
+		return insertErr
+	}
+	defer stmt.Close()
+
+	resolved := make([][]interface{}, len(records))
+	for i, rec := range records {
+		values, err := f.resolveValues(refs, tableName, offset+i, rec, columns)
+		if err != nil {
+			return err
+		}
+		resolved[i] = values
+
+		args := f.convertValues(values)
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			insertErr := newInsertError(tableName, offset+i, rec, err)
+			insertErr.Query, insertErr.Args = query, args
+			return insertErr
+		}
+	}
+
+	// COPY の完了時のエラーは送信したどの行が原因か判別できないため先頭のレコードで報告する
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		insertErr := newInsertError(tableName, offset, records[0], err)
+		insertErr.Query = query
+		if len(records) > 1 {
+			insertErr.location = batchLocation(records)
+		}
+		return insertErr
+	}
+
+	for i, rec := range records {
+		refs.store(rec, columns, resolved[i], nil)
+	}
+
+	return nil
+}
+
+// batchRows は1つのINSERT文にまとめるレコード数を決定する
+func (f *Fixture) batchRows(columnCount int) int {
+	limiter, ok := f.dialect.(BatchLimiter)
+	if !ok || columnCount == 0 {
+		return 1
+	}
+
+	rows := f.batchSize
+	if rows <= 0 {
+		rows = defaultBatchSize
+	}
+
+	maxParams, maxRows := limiter.BatchLimits()
+	if maxParams > 0 {
+		rows = min(rows, maxParams/columnCount)
+	}
+	if maxRows > 0 {
+		rows = min(rows, maxRows)
+	}
+
+	return max(rows, 1)
+}
+
+// resolveValues はレコードの値をカラム順に並べ、参照を解決して値を変換する（index はテーブル内の位置）
+func (f *Fixture) resolveValues(refs *refResolver, tableName string, index int, rec *record, columns []string) ([]interface{}, error) {
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		value, err := refs.resolve(rec.values[col])
+		if err != nil {
+			insertErr := newInsertError(tableName, index, rec, err)
+			insertErr.Column = col
+			return nil, insertErr
+		}
+
+		value, err = f.convertValue(tableName, col, value)
+		if err != nil {
+			insertErr := newInsertError(tableName, index, rec, fmt.Errorf("failed to convert value: %w", err))
+			insertErr.Column = col
+			return nil, insertErr
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// convertValues は方言に合わせてバインドする値を変換する
+func (f *Fixture) convertValues(values []interface{}) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = f.dialect.ConvertValue(value)
+	}
+	return args
+}
+
+// bindValues は1件分の値をプレースホルダーとバインドする引数に変換する
+// n は最初のプレースホルダーの番号で、生のSQL式（!sql）はプレースホルダーの代わりにそのまま埋め込む
+func (f *Fixture) bindValues(values []interface{}, n int) ([]string, []interface{}) {
+	placeholders := make([]string, len(values))
+	args := make([]interface{}, 0, len(values))
+	for i, value := range values {
+		if raw, ok := value.(rawSQL); ok {
+			placeholders[i] = string(raw)
+			continue
+		}
+
+		placeholders[i] = f.dialect.Placeholder(n)
+		args = append(args, f.dialect.ConvertValue(value))
+		n++
+	}
+	return placeholders, args
+}
+
+// buildInsertQuery は指定カラムに rows の各行を挿入するINSERT文を組み立てる
+// rows は bindValues で作成した行ごとのプレースホルダー
+func (f *Fixture) buildInsertQuery(tableName string, columns []string, rows [][]string) string {
+	tuples := make([]string, len(rows))
+	for row, placeholders := range rows {
+		tuples[row] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		f.dialect.QuoteIdentifier(tableName),
+		quoteColumns(f.dialect, columns),
+		strings.Join(tuples, ", "))
+}
+
+// unionColumns は全レコードのカラムを最初に現れた順で重複なく返す
+func unionColumns(records []*record) []string {
+	seen := make(map[string]bool)
+	columns := make([]string, 0)
+	for _, rec := range records {
+		for _, col := range rec.columns {
+			if !seen[col] {
+				seen[col] = true
+				columns = append(columns, col)
+			}
+		}
+	}
+	return columns
+}
+
+// equalColumns はカラム構成が同じかどうかを判定する
+func equalColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// batchLocation は複数レコードの記述位置を "ファイル名:開始行-終了行" 形式で返す
+func batchLocation(records []*record) string {
+	first, last := records[0], records[len(records)-1]
+	if first.source != last.source {
+		return first.location() + " - " + last.location()
+	}
+	return fmt.Sprintf("%s-%d", first.location(), last.line)
+}
+
+// stmtCache はクエリ文字列ごとにプリペアドステートメントを再利用する
+type stmtCache struct {
+	executor Executor
+	stmts    map[string]*sql.Stmt
+}
+
+// newStmtCache は新しい stmtCache を作成する
+func newStmtCache(executor Executor) *stmtCache {
+	return &stmtCache{executor: executor, stmts: make(map[string]*sql.Stmt)}
+}
+
+// prepare はキャッシュ済みのステートメントを返し、なければ準備する
+func (c *stmtCache) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	if stmt, ok := c.stmts[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := c.executor.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[query] = stmt
+	return stmt, nil
+}
+
+// close はキャッシュしたステートメントをすべて閉じる
+func (c *stmtCache) close() {
+	for _, stmt := range c.stmts {
+		stmt.Close()
+	}
+}
@@ -0,0 +1,81 @@
+package yamlfix
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+// fixtureLayer はセーブポイント上に積まれたフィクスチャのレイヤー
+type fixtureLayer struct {
+	name      string
+	savepoint string
+}
+
+// PushLayer は現在のトランザクションにセーブポイントを作成し、yamlPaths のフィクスチャを追加で挿入する
+// 挿入したデータは PopLayer でセーブポイントまでロールバックされ、ベースのフィクスチャは残る
+func (tf *TestFixture) PushLayer(t testing.TB, name string, yamlPaths ...string) {
+	t.Helper()
+
+	if !tf.HasTransaction() {
+		t.Fatalf("failed to push fixture layer %s: transaction not started", name)
+	}
+
+	// レイヤーのフィクスチャはベースとは別に読み込み、同じトランザクションで挿入する
+	layer := tf.newChild()
+	if err := layer.loadPatterns(layer.fsys, yamlPaths); err != nil {
+		t.Fatalf("failed to load fixtures for layer %s: %v", name, err)
+	}
+
+	savepoint := fmt.Sprintf("yamlfix_layer_%d", len(tf.layers)+1)
+	if err := tf.Savepoint(tf.ctx, savepoint); err != nil {
+		t.Fatalf("failed to push fixture layer %s: %v", name, err)
+	}
+	tf.layers = append(tf.layers, fixtureLayer{name: name, savepoint: savepoint})
+
+	if err := layer.InsertFixturesContext(tf.ctx); err != nil {
+		t.Fatalf("failed to insert fixtures for layer %s: %v", name, err)
+	}
+}
+
+// PopLayer は最後に追加したレイヤーをセーブポイントまでロールバックして取り除く
+func (tf *TestFixture) PopLayer(t testing.TB) {
+	t.Helper()
+
+	if len(tf.layers) == 0 {
+		t.Fatalf("failed to pop fixture layer: no layer pushed")
+	}
+
+	top := tf.layers[len(tf.layers)-1]
+	tf.layers = tf.layers[:len(tf.layers)-1]
+
+	if err := tf.RollbackToSavepoint(tf.ctx, top.savepoint); err != nil {
+		t.Fatalf("failed to pop fixture layer %s: %v", top.name, err)
+	}
+	if err := tf.ReleaseSavepoint(tf.ctx, top.savepoint); err != nil {
+		t.Fatalf("failed to pop fixture layer %s: %v", top.name, err)
+	}
+}
+
+// RunLayer はレイヤーを積んだ状態でサブテストを実行し、終了時にレイヤーを取り除く
+// レイヤーは1つのトランザクションを共有するため、サブテストを並列実行してはならない
+func (tf *TestFixture) RunLayer(t *testing.T, name string, yamlPaths []string, testFn func(t *testing.T, tx *sql.Tx)) bool {
+	t.Helper()
+
+	return t.Run(name, func(t *testing.T) {
+		tf.PushLayer(t, name, yamlPaths...)
+		defer tf.PopLayer(t)
+
+		testFn(t, tf.tx)
+	})
+}
+
+// newChild は設定とトランザクションを引き継いだ、フィクスチャが空の Fixture を作成する
+func (f *Fixture) newChild() *Fixture {
+	c := *f
+	c.tableOrder = nil
+	c.fixtures = make(map[string][]*record)
+	c.sources = make(map[string][]string)
+	c.insertedTables = nil
+	return &c
+}
@@ -0,0 +1,123 @@
+package yamlfix
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DuplicateTablePolicy は同じテーブルが複数のファイルで定義された場合の扱いを表す
+type DuplicateTablePolicy int
+
+const (
+	// ErrorOnDuplicateTables は同じテーブルが複数のファイルで定義されている場合にエラーにする
+	ErrorOnDuplicateTables DuplicateTablePolicy = iota
+	// ReplaceDuplicateTables は後から読み込んだファイルのレコードで置き換える
+	ReplaceDuplicateTables
+	// AppendDuplicateTables は後から読み込んだファイルのレコードを末尾に追加する
+	AppendDuplicateTables
+	// MergeDuplicateTablesByPrimaryKey は主キーが同じレコードを後から読み込んだレコードで置き換え、それ以外は末尾に追加する
+	MergeDuplicateTablesByPrimaryKey
+)
+
+// defaultPrimaryKey は Config.PrimaryKeys に指定のないテーブルの主キー
+var defaultPrimaryKey = []string{"id"}
+
+// mergeTable は読み込んだレコードを重複テーブルの扱いに従って既存のデータにマージする
+func (f *Fixture) mergeTable(tableName string, records []*record, source string) error {
+	if f.fixtures == nil {
+		f.fixtures = make(map[string][]*record)
+	}
+	if f.sources == nil {
+		f.sources = make(map[string][]string)
+	}
+
+	existing, ok := f.fixtures[tableName]
+	if !ok {
+		f.fixtures[tableName] = records
+		f.sources[tableName] = []string{source}
+		f.updateTableOrder(tableName)
+		return nil
+	}
+
+	switch f.duplicateTables {
+	case ReplaceDuplicateTables:
+		f.fixtures[tableName] = records
+		f.sources[tableName] = []string{source}
+	case AppendDuplicateTables:
+		f.fixtures[tableName] = append(existing[:len(existing):len(existing)], records...)
+		f.sources[tableName] = append(f.sources[tableName], source)
+	case MergeDuplicateTablesByPrimaryKey:
+		merged, err := f.mergeByPrimaryKey(tableName, existing, records)
+		if err != nil {
+			return err
+		}
+		f.fixtures[tableName] = merged
+		f.sources[tableName] = append(f.sources[tableName], source)
+	default:
+		return loadErrorf(source, 0, "", "table %s is defined in both %s and %s", tableName,
+			strings.Join(displayNames(f.sources[tableName]), ", "), displayName(source))
+	}
+
+	return nil
+}
+
+// mergeByPrimaryKey は主キーが一致するレコードを置き換え、一致しないレコードを末尾に追加する
+func (f *Fixture) mergeByPrimaryKey(tableName string, existing, records []*record) ([]*record, error) {
+	primaryKey := f.primaryKey(tableName)
+
+	merged := append([]*record(nil), existing...)
+	index := make(map[string]int, len(merged))
+	for i, rec := range merged {
+		key, err := primaryKeyOf(rec, primaryKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge table %s: %w", tableName, err)
+		}
+		index[key] = i
+	}
+
+	for _, rec := range records {
+		key, err := primaryKeyOf(rec, primaryKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge table %s: %w", tableName, err)
+		}
+
+		if i, ok := index[key]; ok {
+			merged[i] = rec
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, rec)
+	}
+
+	return merged, nil
+}
+
+// primaryKey はテーブルの主キーのカラムを返す
+func (f *Fixture) primaryKey(tableName string) []string {
+	if columns, ok := f.primaryKeys[tableName]; ok && len(columns) > 0 {
+		return columns
+	}
+	return defaultPrimaryKey
+}
+
+// primaryKeyOf はレコードの主キーの値を比較用の文字列で返す
+func primaryKeyOf(rec *record, primaryKey []string) (string, error) {
+	parts := make([]string, len(primaryKey))
+	for i, col := range primaryKey {
+		value, ok := rec.values[col]
+		if !ok {
+			return "", loadErrorf(rec.source, rec.line, "", "record has no primary key column %s", col)
+		}
+		parts[i] = fmt.Sprintf("%T:%v", value, value)
+	}
+	return strings.Join(parts, "\x00"), nil
+}
+
+// displayNames はエラーメッセージ用のファイル名一覧を返す
+func displayNames(filenames []string) []string {
+	names := make([]string, len(filenames))
+	for i, filename := range filenames {
+		names[i] = displayName(filename)
+	}
+	return names
+}
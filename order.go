@@ -0,0 +1,225 @@
+package yamlfix
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ForeignKeyIntrospector は外部キーの参照関係をスキーマから取得できる方言が実装するインターフェース
+type ForeignKeyIntrospector interface {
+	// ReferencedTables は table が外部キーで参照しているテーブル名を返す
+	ReferencedTables(ctx context.Context, executor Executor, table string) ([]string, error)
+}
+
+// ConstraintDeferrer は外部キー制約の検査を遅延できる方言が実装するインターフェース
+type ConstraintDeferrer interface {
+	// DeferConstraints は制約検査を遅延し、元に戻す関数を返す
+	DeferConstraints(ctx context.Context, executor Executor) (restore func() error, err error)
+}
+
+// ReferencedTables は pragma_foreign_key_list から参照先テーブルを取得する
+func (SQLiteDialect) ReferencedTables(ctx context.Context, executor Executor, table string) ([]string, error) {
+	return queryStrings(ctx, executor, `SELECT DISTINCT "table" FROM pragma_foreign_key_list(?)`, table)
+}
+
+// DeferConstraints は defer_foreign_keys によりコミット時まで外部キー検査を遅延する
+func (SQLiteDialect) DeferConstraints(ctx context.Context, executor Executor) (func() error, error) {
+	return execWithRestore(ctx, executor, "PRAGMA defer_foreign_keys = ON", "PRAGMA defer_foreign_keys = OFF")
+}
+
+// ReferencedTables は information_schema から参照先テーブルを取得する
+func (MySQLDialect) ReferencedTables(ctx context.Context, executor Executor, table string) ([]string, error) {
+	return queryStrings(ctx, executor, `
+		SELECT DISTINCT REFERENCED_TABLE_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE()
+		  AND TABLE_NAME = ?
+		  AND REFERENCED_TABLE_NAME IS NOT NULL`, table)
+}
+
+// DeferConstraints は FOREIGN_KEY_CHECKS を無効化する
+// MySQLは再有効化時に既存行を検査しないため、循環参照のデータ整合性は利用者の責任となる
+func (MySQLDialect) DeferConstraints(ctx context.Context, executor Executor) (func() error, error) {
+	return execWithRestore(ctx, executor, "SET FOREIGN_KEY_CHECKS = 0", "SET FOREIGN_KEY_CHECKS = 1")
+}
+
+// ReferencedTables は information_schema から参照先テーブルを取得する
+func (PostgreSQLDialect) ReferencedTables(ctx context.Context, executor Executor, table string) ([]string, error) {
+	return queryStrings(ctx, executor, `
+		SELECT DISTINCT ccu.table_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.constraint_column_usage ccu
+		  ON ccu.constraint_schema = tc.constraint_schema
+		 AND ccu.constraint_name = tc.constraint_name
+		WHERE tc.constraint_type = 'FOREIGN KEY'
+		  AND tc.table_schema = current_schema()
+		  AND tc.table_name = $1`, table)
+}
+
+// DeferConstraints は DEFERRABLE な制約の検査をトランザクション内で遅延する
+func (PostgreSQLDialect) DeferConstraints(ctx context.Context, executor Executor) (func() error, error) {
+	return execWithRestore(ctx, executor, "SET CONSTRAINTS ALL DEFERRED", "SET CONSTRAINTS ALL IMMEDIATE")
+}
+
+// ReferencedTables は sys.foreign_keys から参照先テーブルを取得する
+func (SQLServerDialect) ReferencedTables(ctx context.Context, executor Executor, table string) ([]string, error) {
+	return queryStrings(ctx, executor, `
+		SELECT DISTINCT OBJECT_NAME(referenced_object_id)
+		FROM sys.foreign_keys
+		WHERE parent_object_id = OBJECT_ID(@p1)`, table)
+}
+
+// ReferencedTables は user_constraints から参照先テーブルを取得する
+func (OracleDialect) ReferencedTables(ctx context.Context, executor Executor, table string) ([]string, error) {
+	return queryStrings(ctx, executor, `
+		SELECT DISTINCT r.table_name
+		FROM user_constraints c
+		JOIN user_constraints r ON r.constraint_name = c.r_constraint_name
+		WHERE c.constraint_type = 'R'
+		  AND c.table_name = UPPER(:1)`, table)
+}
+
+// DeferConstraints は DEFERRABLE な制約の検査をトランザクション内で遅延する
+func (OracleDialect) DeferConstraints(ctx context.Context, executor Executor) (func() error, error) {
+	return execWithRestore(ctx, executor, "SET CONSTRAINTS ALL DEFERRED", "SET CONSTRAINTS ALL IMMEDIATE")
+}
+
+// sortTables は外部キーの参照関係に従ってテーブルの挿入順序を決定する
+// 循環参照がある場合は、循環に含まれるテーブルを元の順序のまま末尾に並べた順序と *CycleError を返す
+func (f *Fixture) sortTables(ctx context.Context, executor Executor) ([]string, error) {
+	// $ref による参照先のテーブルは先に挿入する
+	deps := f.referenceDeps()
+
+	introspector, ok := f.dialect.(ForeignKeyIntrospector)
+	if !ok {
+		return topologicalSort(f.tableOrder, deps)
+	}
+
+	// テーブル名は大文字小文字を区別せずに照合する
+	known := make(map[string]string, len(f.tableOrder))
+	for _, tableName := range f.tableOrder {
+		known[strings.ToLower(tableName)] = tableName
+	}
+
+	for _, tableName := range f.tableOrder {
+		referenced, err := introspector.ReferencedTables(ctx, executor, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read foreign keys of table %s: %w", tableName, err)
+		}
+
+		for _, ref := range referenced {
+			dep, ok := known[strings.ToLower(ref)]
+			if ok && dep != tableName && !containsString(deps[tableName], dep) {
+				deps[tableName] = append(deps[tableName], dep)
+			}
+		}
+	}
+
+	return topologicalSort(f.tableOrder, deps)
+}
+
+// topologicalSort は依存先が先に来るように tables を並べ替える
+// 依存関係のないテーブル同士は元の順序を保つ
+func topologicalSort(tables []string, deps map[string][]string) ([]string, error) {
+	placed := make(map[string]bool, len(tables))
+	order := make([]string, 0, len(tables))
+
+	for len(order) < len(tables) {
+		progressed := false
+		for _, tableName := range tables {
+			if placed[tableName] || !allPlaced(deps[tableName], placed) {
+				continue
+			}
+			placed[tableName] = true
+			order = append(order, tableName)
+			progressed = true
+			break
+		}
+
+		if !progressed {
+			remaining := make([]string, 0, len(tables)-len(order))
+			for _, tableName := range tables {
+				if !placed[tableName] {
+					remaining = append(remaining, tableName)
+				}
+			}
+			return append(order, remaining...), &CycleError{Tables: findCycle(remaining, deps, placed)}
+		}
+	}
+
+	return order, nil
+}
+
+// allPlaced は依存先がすべて配置済みかどうかを判定する
+func allPlaced(deps []string, placed map[string]bool) bool {
+	for _, dep := range deps {
+		if !placed[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// findCycle は未配置のテーブルから循環参照の経路を1つ探す
+func findCycle(remaining []string, deps map[string][]string, placed map[string]bool) []string {
+	// 未配置のテーブルは必ず未配置の依存先を持つため、辿り続ければ循環に到達する
+	visited := make(map[string]int)
+	path := make([]string, 0)
+	current := remaining[0]
+	for {
+		if i, ok := visited[current]; ok {
+			return append(path[i:], current)
+		}
+		visited[current] = len(path)
+		path = append(path, current)
+
+		for _, dep := range deps[current] {
+			if !placed[dep] {
+				current = dep
+				break
+			}
+		}
+	}
+}
+
+// CycleError は外部キーや $ref の循環参照によりテーブルの挿入順序を決定できないことを表す
+type CycleError struct {
+	Tables []string // 循環の経路（先頭と末尾は同じテーブル）
+}
+
+// Error はエラーメッセージを返す
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("table dependency cycle detected: %s (set Config.DeferConstraints to insert these tables with deferred constraints)",
+		strings.Join(e.Tables, " -> "))
+}
+
+// queryStrings は1カラムの文字列結果を持つクエリを実行する
+func queryStrings(ctx context.Context, executor Executor, query string, args ...interface{}) ([]string, error) {
+	rows, err := executor.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// execWithRestore は stmt を実行し、restoreStmt を実行する関数を返す
+func execWithRestore(ctx context.Context, executor Executor, stmt, restoreStmt string) (func() error, error) {
+	if _, err := executor.ExecContext(ctx, stmt); err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := executor.ExecContext(ctx, restoreStmt)
+		return err
+	}, nil
+}
@@ -0,0 +1,33 @@
+package yamlfix
+
+// rawSQLTag はバインドパラメータではなくSQL式として挿入する値のYAMLタグ
+const rawSQLTag = "!sql"
+
+// rawSQL は VALUES 句にそのまま埋め込むSQL式（CURRENT_TIMESTAMP など）
+type rawSQL string
+
+// containsRawSQL はレコード群に生のSQL式が含まれるかを判定する
+func containsRawSQL(records []*record) bool {
+	for _, rec := range records {
+		for _, value := range rec.values {
+			if _, ok := value.(rawSQL); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rejectRawSQL は生のSQL式を含むレコードがあればエラーを返す
+func rejectRawSQL(tables []tableData) error {
+	for _, table := range tables {
+		for _, rec := range table.records {
+			for _, col := range rec.columns {
+				if _, ok := rec.values[col].(rawSQL); ok {
+					return loadErrorf(rec.source, rec.line, col, "raw SQL value is not allowed because Config.DisableRawSQL is set")
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package yamlfix
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// record はYAMLに記述された1レコード分のデータ
+type record struct {
+	columns []string               // YAMLでの記述順のカラム名
+	values  map[string]interface{} // カラム名と値の対応
+	label   string                 // 他のレコードから参照するためのラベル（_label）
+	deleted bool                   // include / extends で読み込んだレコードを削除する指定（_delete）
+	source  string                 // 読み込み元のファイル名
+	line    int                    // YAML上の行番号
+}
+
+// location はレコードの記述位置を "ファイル名:行番号" 形式で返す
+func (r *record) location() string {
+	return fmt.Sprintf("%s:%d", displayName(r.source), r.line)
+}
+
+// tableData はYAMLから読み込んだ1テーブル分のデータ
+type tableData struct {
+	name     string
+	records  []*record
+	defaults *record // 各レコードに適用するデフォルト値（_defaults）
+}
+
+// documentRoot はドキュメントノードの最上位要素を返す（空のドキュメントの場合は nil）
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// decodeTables は複数テーブル形式のマッピングノードを記述順のテーブル一覧に変換する
+func decodeTables(root *yaml.Node, filename string) ([]tableData, error) {
+	tables := make([]tableData, 0, len(root.Content)/2)
+	seen := make(map[string]bool, len(root.Content)/2)
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		keyNode, valueNode := root.Content[i], root.Content[i+1]
+		tableName := keyNode.Value
+
+		if seen[tableName] {
+			return nil, loadErrorf(filename, keyNode.Line, "", "table %s is defined more than once", tableName)
+		}
+		seen[tableName] = true
+
+		records, defaults, err := decodeRecords(valueNode, filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse table %s: %w", tableName, err)
+		}
+
+		tables = append(tables, tableData{name: tableName, records: records, defaults: defaults})
+	}
+
+	return tables, nil
+}
+
+// decodeRecords はレコードのシーケンスノードをレコード一覧とデフォルト値に変換する
+// 先頭の要素が _defaults のみのマッピングの場合はデフォルト値として扱う
+func decodeRecords(node *yaml.Node, filename string) ([]*record, *record, error) {
+	node = resolveAlias(node)
+	if node == nil || isNullNode(node) {
+		return nil, nil, nil
+	}
+
+	if node.Kind != yaml.SequenceNode {
+		return nil, nil, loadErrorf(filename, node.Line, "", "expected a sequence of records")
+	}
+
+	items := node.Content
+	var defaults *record
+	if len(items) > 0 && isDefaultsNode(resolveAlias(items[0])) {
+		var err error
+		defaults, err = decodeDefaults(resolveAlias(items[0]).Content[1], filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = items[1:]
+	}
+
+	records := make([]*record, 0, len(items))
+	labels := make(map[string]*record)
+	for _, item := range items {
+		rec, err := decodeRecord(item, filename)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if rec.label != "" {
+			if prev, ok := labels[rec.label]; ok {
+				return nil, nil, loadErrorf(filename, rec.line, "", "label %s is already defined at line %d", rec.label, prev.line)
+			}
+			labels[rec.label] = rec
+		}
+		records = append(records, rec)
+	}
+
+	return records, defaults, nil
+}
+
+// decodeRecord はマッピングノードをカラムの記述順を保持したレコードに変換する
+// エイリアス（*name）は参照先のマッピングとして、マージキー（<<）は展開したカラムとして扱う
+func decodeRecord(node *yaml.Node, filename string) (*record, error) {
+	line := node.Line
+	content, err := expandMapping(node, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &record{
+		columns: make([]string, 0, len(content)/2),
+		values:  make(map[string]interface{}, len(content)/2),
+		source:  filename,
+		line:    line,
+	}
+
+	for i := 0; i+1 < len(content); i += 2 {
+		keyNode, valueNode := content[i], resolveAlias(content[i+1])
+		column := keyNode.Value
+
+		if _, ok := rec.values[column]; ok {
+			return nil, loadErrorf(filename, keyNode.Line, column, "defined more than once")
+		}
+
+		if column == defaultsKey {
+			return nil, loadErrorf(filename, keyNode.Line, "", "%s must be the first item of the table", defaultsKey)
+		}
+
+		// ラベルはカラムではなくレコードの識別子として扱う
+		if column == labelKey {
+			if valueNode.Kind != yaml.ScalarNode || valueNode.Value == "" {
+				return nil, loadErrorf(filename, valueNode.Line, "", "%s must be a non-empty scalar", labelKey)
+			}
+			rec.label = valueNode.Value
+			continue
+		}
+
+		// 削除の指定はカラムではなく継承したレコードへの操作として扱う
+		if column == deleteKey {
+			if err := valueNode.Decode(&rec.deleted); err != nil {
+				return nil, loadErrorf(filename, valueNode.Line, "", "%s must be a boolean", deleteKey)
+			}
+			continue
+		}
+
+		// !sql タグの値はバインドせずにSQL式としてそのまま挿入する
+		if valueNode.Tag == rawSQLTag {
+			if valueNode.Kind != yaml.ScalarNode || valueNode.Value == "" {
+				return nil, loadErrorf(filename, valueNode.Line, column, "%s value must be a non-empty scalar", rawSQLTag)
+			}
+			rec.columns = append(rec.columns, column)
+			rec.values[column] = rawSQL(valueNode.Value)
+			continue
+		}
+
+		value, err := decodeValue(valueNode)
+		if err != nil {
+			return nil, &LoadError{SourceFile: filename, Line: valueNode.Line, Column: column, Err: err}
+		}
+
+		// $ref(テーブル名.ラベル.カラム名) は挿入時に参照先の値へ解決する
+		ref, ok, err := parseReference(value)
+		if err != nil {
+			return nil, &LoadError{SourceFile: filename, Line: valueNode.Line, Column: column, Err: err}
+		}
+		if ok {
+			value = ref
+		}
+
+		rec.columns = append(rec.columns, column)
+		rec.values[column] = value
+	}
+
+	return rec, nil
+}
+
+// expandMapping はマッピングノードのキーと値を、マージキーを展開して記述順に返す
+// マージしたカラムは同じマッピングに明示したカラムより優先度が低く、複数のマージ元では先に記述したものが優先される
+func expandMapping(node *yaml.Node, filename string) ([]*yaml.Node, error) {
+	line := node.Line
+	node = resolveAlias(node)
+	if node.Kind != yaml.MappingNode {
+		return nil, loadErrorf(filename, line, "", "expected a mapping")
+	}
+
+	explicit := make(map[string]bool, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !isMergeKey(node.Content[i]) {
+			explicit[node.Content[i].Value] = true
+		}
+	}
+
+	content := make([]*yaml.Node, 0, len(node.Content))
+	merged := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		if !isMergeKey(keyNode) {
+			content = append(content, keyNode, valueNode)
+			continue
+		}
+
+		// << の値はマッピングまたはマッピングのリスト
+		sources := []*yaml.Node{valueNode}
+		if resolved := resolveAlias(valueNode); resolved.Kind == yaml.SequenceNode {
+			sources = resolved.Content
+		}
+		for _, source := range sources {
+			pairs, err := expandMapping(source, filename)
+			if err != nil {
+				return nil, err
+			}
+			for j := 0; j+1 < len(pairs); j += 2 {
+				column := pairs[j].Value
+				if explicit[column] || merged[column] {
+					continue
+				}
+				merged[column] = true
+				content = append(content, pairs[j], pairs[j+1])
+			}
+		}
+	}
+
+	return content, nil
+}
+
+// resolveAlias はエイリアスノードを参照先のノードに解決する
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// isMergeKey はマージキー（<<）かどうかを判定する
+func isMergeKey(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!merge"
+}
+
+// isNullNode は null を表すスカラーノードかどうかを判定する
+func isNullNode(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+// displayName はエラーメッセージ用のファイル名を返す
+func displayName(filename string) string {
+	if filename == "" {
+		return "<yaml>"
+	}
+	return filename
+}
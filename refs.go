@@ -0,0 +1,253 @@
+package yamlfix
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// labelKey はレコードに参照用のラベルを付けるキー（カラムとしては挿入されない）
+const labelKey = "_label"
+
+// refPattern は $ref(テーブル名.ラベル.カラム名) 形式の参照にマッチする
+var refPattern = regexp.MustCompile(`^\$ref\(\s*([^()\s]+)\.([^.()\s]+)\.([^.()\s]+)\s*\)$`)
+
+// reference は $ref(テーブル名.ラベル.カラム名) で記述された他レコードの値への参照
+type reference struct {
+	table  string
+	label  string
+	column string
+}
+
+// String は参照をYAMLでの記述形式で返す
+func (r reference) String() string {
+	return fmt.Sprintf("$ref(%s.%s.%s)", r.table, r.label, r.column)
+}
+
+// parseReference は $ref( で始まる文字列を参照として解析する
+func parseReference(value interface{}) (reference, bool, error) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, "$ref(") {
+		return reference{}, false, nil
+	}
+
+	m := refPattern.FindStringSubmatch(s)
+	if m == nil {
+		return reference{}, false, fmt.Errorf("invalid reference %q: expected $ref(table.label.column)", s)
+	}
+	return reference{table: m[1], label: m[2], column: m[3]}, true, nil
+}
+
+// ReturningInserter は挿入した行で生成された値をINSERT文から返せる方言が実装するインターフェース
+// 実装していない方言では LastInsertId で自動採番カラムの値を取得する
+type ReturningInserter interface {
+	// InsertReturningQuery は returning のカラム値を結果セットとして返すINSERT文を組み立てる
+	// columns と returning はクォート前のカラム名、placeholders はカラム順のプレースホルダー
+	InsertReturningQuery(tableName string, columns, placeholders, returning []string) string
+}
+
+// InsertReturningQuery は RETURNING 句付きのINSERT文を返す
+func (d PostgreSQLDialect) InsertReturningQuery(tableName string, columns, placeholders, returning []string) string {
+	return insertReturningClause(d, tableName, columns, placeholders, returning)
+}
+
+// InsertReturningQuery は RETURNING 句付きのINSERT文を返す（SQLite 3.35以降）
+func (d SQLiteDialect) InsertReturningQuery(tableName string, columns, placeholders, returning []string) string {
+	return insertReturningClause(d, tableName, columns, placeholders, returning)
+}
+
+// InsertReturningQuery は OUTPUT INSERTED 句付きのINSERT文を返す
+func (d SQLServerDialect) InsertReturningQuery(tableName string, columns, placeholders, returning []string) string {
+	output := make([]string, len(returning))
+	for i, col := range returning {
+		output[i] = "INSERTED." + d.QuoteIdentifier(col)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) OUTPUT %s VALUES (%s)",
+		d.QuoteIdentifier(tableName),
+		quoteColumns(d, columns),
+		strings.Join(output, ", "),
+		strings.Join(placeholders, ", "))
+}
+
+// insertReturningClause は末尾に RETURNING 句を持つINSERT文を組み立てる
+func insertReturningClause(d Dialect, tableName string, columns, placeholders, returning []string) string {
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING %s",
+		d.QuoteIdentifier(tableName),
+		quoteColumns(d, columns),
+		strings.Join(placeholders, ", "),
+		quoteColumns(d, returning))
+}
+
+// refResolver はラベル付きレコードの挿入結果を保持し、参照を実際の値に解決する
+type refResolver struct {
+	labeled   map[string]map[string]*record      // テーブル名 → ラベル → レコード
+	returning map[*record][]string               // 挿入時にデータベースから値を取得するカラム
+	inserted  map[*record]map[string]interface{} // 挿入済みのラベル付きレコードの値
+}
+
+// newRefResolver はフィクスチャ内のラベルと参照を検証し、refResolver を作成する
+func (f *Fixture) newRefResolver() (*refResolver, error) {
+	r := &refResolver{
+		labeled:   make(map[string]map[string]*record),
+		returning: make(map[*record][]string),
+		inserted:  make(map[*record]map[string]interface{}),
+	}
+
+	for _, tableName := range f.tableOrder {
+		for _, rec := range f.fixtures[tableName] {
+			if rec.label == "" {
+				continue
+			}
+			if r.labeled[tableName] == nil {
+				r.labeled[tableName] = make(map[string]*record)
+			}
+			if prev, ok := r.labeled[tableName][rec.label]; ok {
+				return nil, fmt.Errorf("label %s.%s is defined more than once at %s and %s", tableName, rec.label, prev.location(), rec.location())
+			}
+			r.labeled[tableName][rec.label] = rec
+		}
+	}
+
+	// 参照先が存在するかを検証し、レコードに記述されていないカラムは挿入時に取得する
+	for _, tableName := range f.tableOrder {
+		for _, rec := range f.fixtures[tableName] {
+			for _, col := range rec.columns {
+				ref, ok := rec.values[col].(reference)
+				if !ok {
+					continue
+				}
+
+				target, ok := r.labeled[ref.table][ref.label]
+				if !ok {
+					return nil, fmt.Errorf("unknown reference %s in column %s at %s", ref, col, rec.location())
+				}
+				// 生のSQL式は挿入時に評価されるため、記述されていないカラムと同様に取得する
+				value, ok := target.values[ref.column]
+				if _, raw := value.(rawSQL); (!ok || raw) && !containsString(r.returning[target], ref.column) {
+					r.returning[target] = append(r.returning[target], ref.column)
+				}
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// isolated はレコードを他のレコードとまとめずに1件ずつ挿入する必要があるかを判定する
+// 生成値の取得が必要なレコードと、同じテーブルのラベルを参照するレコードが該当する
+func (r *refResolver) isolated(tableName string, rec *record) bool {
+	if len(r.returning[rec]) > 0 {
+		return true
+	}
+	for _, value := range rec.values {
+		if ref, ok := value.(reference); ok && ref.table == tableName {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve は参照を挿入済みレコードの値に置き換える（参照以外はそのまま返す）
+func (r *refResolver) resolve(value interface{}) (interface{}, error) {
+	ref, ok := value.(reference)
+	if !ok {
+		return value, nil
+	}
+
+	target := r.labeled[ref.table][ref.label]
+	values, ok := r.inserted[target]
+	if !ok {
+		return nil, fmt.Errorf("reference %s is used before the record at %s is inserted", ref, target.location())
+	}
+	return values[ref.column], nil
+}
+
+// store はラベル付きレコードの挿入後の値を記録する
+func (r *refResolver) store(rec *record, columns []string, values []interface{}, returned map[string]interface{}) {
+	if rec.label == "" {
+		return
+	}
+
+	stored := make(map[string]interface{}, len(columns)+len(returned))
+	for i, col := range columns {
+		stored[col] = values[i]
+	}
+	for col, value := range returned {
+		stored[col] = value
+	}
+	r.inserted[rec] = stored
+}
+
+// referenceDeps は参照によるテーブル間の依存関係を返す
+func (f *Fixture) referenceDeps() map[string][]string {
+	deps := make(map[string][]string)
+	for _, tableName := range f.tableOrder {
+		for _, rec := range f.fixtures[tableName] {
+			for _, value := range rec.values {
+				ref, ok := value.(reference)
+				if !ok || ref.table == tableName || containsString(deps[tableName], ref.table) {
+					continue
+				}
+				if _, ok := f.fixtures[ref.table]; ok {
+					deps[tableName] = append(deps[tableName], ref.table)
+				}
+			}
+		}
+	}
+	return deps
+}
+
+// insertReturning は1件のレコードを挿入し、returning のカラム値を取得する
+// 実行したクエリも返す
+func (f *Fixture) insertReturning(ctx context.Context, executor Executor, tableName string, columns, placeholders []string, args []interface{}, returning []string) (string, map[string]interface{}, error) {
+	returned := make(map[string]interface{}, len(returning))
+
+	inserter, ok := f.dialect.(ReturningInserter)
+	if !ok {
+		// RETURNING に対応しない方言では自動採番カラムの値のみ取得できる
+		if len(returning) != 1 {
+			return "", nil, fmt.Errorf("dialect %s can only resolve the auto-increment column, but %s were requested", f.dialect.Name(), strings.Join(returning, ", "))
+		}
+
+		query := f.buildInsertQuery(tableName, columns, [][]string{placeholders})
+		result, err := executor.ExecContext(ctx, query, args...)
+		if err != nil {
+			return query, nil, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return query, nil, fmt.Errorf("failed to get last insert id for %s: %w", returning[0], err)
+		}
+		returned[returning[0]] = id
+		return query, returned, nil
+	}
+
+	dest := make([]interface{}, len(returning))
+	for i := range dest {
+		dest[i] = new(interface{})
+	}
+	query := inserter.InsertReturningQuery(tableName, columns, placeholders, returning)
+	if err := executor.QueryRowContext(ctx, query, args...).Scan(dest...); err != nil {
+		return query, nil, err
+	}
+
+	for i, col := range returning {
+		value := *(dest[i].(*interface{}))
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		returned[col] = value
+	}
+	return query, returned, nil
+}
+
+// containsString は values に s が含まれるかを判定する
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
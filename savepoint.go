@@ -0,0 +1,80 @@
+package yamlfix
+
+import (
+	"context"
+	"fmt"
+)
+
+// Savepointer はセーブポイントの構文が標準SQLと異なる方言が実装するインターフェース
+// 実装していない方言では SAVEPOINT / ROLLBACK TO SAVEPOINT / RELEASE SAVEPOINT を使う
+type Savepointer interface {
+	// SavepointSQL はセーブポイントを作成するSQLを返す
+	SavepointSQL(name string) string
+	// RollbackToSavepointSQL はセーブポイントまでロールバックするSQLを返す
+	RollbackToSavepointSQL(name string) string
+	// ReleaseSavepointSQL はセーブポイントを解放するSQLを返す（空文字の場合は何もしない）
+	ReleaseSavepointSQL(name string) string
+}
+
+// SavepointSQL は SAVE TRANSACTION を返す
+func (SQLServerDialect) SavepointSQL(name string) string { return "SAVE TRANSACTION " + name }
+
+// RollbackToSavepointSQL は ROLLBACK TRANSACTION を返す
+func (SQLServerDialect) RollbackToSavepointSQL(name string) string {
+	return "ROLLBACK TRANSACTION " + name
+}
+
+// ReleaseSavepointSQL は空文字を返す（SQL Serverにはセーブポイントの解放がない）
+func (SQLServerDialect) ReleaseSavepointSQL(string) string { return "" }
+
+// SavepointSQL は SAVEPOINT を返す
+func (OracleDialect) SavepointSQL(name string) string { return "SAVEPOINT " + name }
+
+// RollbackToSavepointSQL は ROLLBACK TO SAVEPOINT を返す
+func (OracleDialect) RollbackToSavepointSQL(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + name
+}
+
+// ReleaseSavepointSQL は空文字を返す（Oracleにはセーブポイントの解放がない）
+func (OracleDialect) ReleaseSavepointSQL(string) string { return "" }
+
+// Savepoint は現在のトランザクションにセーブポイントを作成する
+func (f *Fixture) Savepoint(ctx context.Context, name string) error {
+	create, _, _ := f.savepointSQL(name)
+	return f.execInTransaction(ctx, create, "create savepoint "+name)
+}
+
+// RollbackToSavepoint はセーブポイント作成時点までトランザクションをロールバックする
+func (f *Fixture) RollbackToSavepoint(ctx context.Context, name string) error {
+	_, rollback, _ := f.savepointSQL(name)
+	return f.execInTransaction(ctx, rollback, "rollback to savepoint "+name)
+}
+
+// ReleaseSavepoint はセーブポイントを解放する
+func (f *Fixture) ReleaseSavepoint(ctx context.Context, name string) error {
+	_, _, release := f.savepointSQL(name)
+	if release == "" {
+		return nil
+	}
+	return f.execInTransaction(ctx, release, "release savepoint "+name)
+}
+
+// savepointSQL は方言に合わせたセーブポイントの作成・ロールバック・解放のSQLを返す（解放がない場合は空文字）
+func (f *Fixture) savepointSQL(name string) (create, rollback, release string) {
+	if sp, ok := f.dialect.(Savepointer); ok {
+		return sp.SavepointSQL(name), sp.RollbackToSavepointSQL(name), sp.ReleaseSavepointSQL(name)
+	}
+	return "SAVEPOINT " + name, "ROLLBACK TO SAVEPOINT " + name, "RELEASE SAVEPOINT " + name
+}
+
+// execInTransaction はトランザクション内でSQLを実行する
+func (f *Fixture) execInTransaction(ctx context.Context, query, action string) error {
+	if f.tx == nil {
+		return fmt.Errorf("transaction not started")
+	}
+
+	if _, err := f.tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	return nil
+}
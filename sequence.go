@@ -0,0 +1,90 @@
+package yamlfix
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// SequenceResetter はIDを明示して挿入した後に自動採番の次の値を進める方言が実装するインターフェース
+type SequenceResetter interface {
+	// ResetSequence は table の自動採番の次の値を既存の最大値より大きくする
+	ResetSequence(ctx context.Context, executor Executor, table string) error
+}
+
+// ResetSequence は serial / identity カラムのシーケンスを setval で MAX + 1 に設定する
+// setval はトランザクションをロールバックしても元に戻らない
+func (d PostgreSQLDialect) ResetSequence(ctx context.Context, executor Executor, table string) error {
+	quoted := d.QuoteIdentifier(table)
+	// 同じパラメータを regclass と text の両方に使うと型が決まらないため、テーブル名を別々に渡す
+	columns, err := queryStrings(ctx, executor, `
+		SELECT a.attname
+		FROM pg_attribute a
+		WHERE a.attrelid = $1::regclass
+		  AND a.attnum > 0
+		  AND NOT a.attisdropped
+		  AND pg_get_serial_sequence($2, a.attname) IS NOT NULL`, quoted, quoted)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		query := fmt.Sprintf("SELECT setval(pg_get_serial_sequence($1, $2), (SELECT COALESCE(MAX(%s), 0) + 1 FROM %s), false)",
+			d.QuoteIdentifier(column), quoted)
+		if _, err := executor.ExecContext(ctx, query, quoted, column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResetSequence は AUTO_INCREMENT を 1 に設定し、MySQLに MAX + 1 へ補正させる
+// ALTER TABLE は暗黙のコミットを伴うため、トランザクション内では何もしない
+func (d MySQLDialect) ResetSequence(ctx context.Context, executor Executor, table string) error {
+	if _, ok := executor.(*sql.Tx); ok {
+		return nil
+	}
+	_, err := executor.ExecContext(ctx, "ALTER TABLE "+d.QuoteIdentifier(table)+" AUTO_INCREMENT = 1")
+	return err
+}
+
+// ResetSequence は AUTOINCREMENT のテーブルの sqlite_sequence を最大の rowid まで進める
+func (d SQLiteDialect) ResetSequence(ctx context.Context, executor Executor, table string) error {
+	// sqlite_sequence には AUTOINCREMENT のテーブルに1件以上挿入した後にエントリが作られる
+	exists, err := queryStrings(ctx, executor, `SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'sqlite_sequence'`)
+	if err != nil || len(exists) == 0 {
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE sqlite_sequence SET seq = (SELECT MAX(rowid) FROM %s) WHERE name = ? AND seq < (SELECT MAX(rowid) FROM %s)",
+		d.QuoteIdentifier(table), d.QuoteIdentifier(table))
+	_, err = executor.ExecContext(ctx, query, table)
+	return err
+}
+
+// ResetSequence は IDENTITY カラムを持つテーブルの採番を DBCC CHECKIDENT で最大値に合わせる
+func (d SQLServerDialect) ResetSequence(ctx context.Context, executor Executor, table string) error {
+	name := d.tableLiteral(table)
+	query := fmt.Sprintf("IF OBJECTPROPERTY(OBJECT_ID(%s), 'TableHasIdentity') = 1 DBCC CHECKIDENT (%s, RESEED)", name, name)
+	_, err := executor.ExecContext(ctx, query)
+	return err
+}
+
+// tableLiteral は OBJECT_ID や DBCC に渡すクォート済みのテーブル名を文字列リテラルにする
+func (d SQLServerDialect) tableLiteral(table string) string {
+	return "'" + strings.ReplaceAll(d.QuoteIdentifier(table), "'", "''") + "'"
+}
+
+// resetSequence は方言が対応している場合に table の自動採番を挿入済みの値より先に進める
+func (f *Fixture) resetSequence(ctx context.Context, executor Executor, tableName string) error {
+	resetter, ok := f.dialect.(SequenceResetter)
+	if !ok || f.disableSequenceReset {
+		return nil
+	}
+
+	if err := resetter.ResetSequence(ctx, executor, tableName); err != nil {
+		return fmt.Errorf("failed to reset sequence of table %s: %w", tableName, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package yamlfix
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// FixtureSet は読み込み済みのフィクスチャを保持する不変の集合
+// 複数のゴルーチンから同時に Session を作成して使用できる
+type FixtureSet struct {
+	fixture *Fixture
+}
+
+// Freeze は現在読み込まれているフィクスチャから不変の FixtureSet を作成する
+// 作成後に Fixture へ追加で読み込んだデータは FixtureSet に影響しない
+func (f *Fixture) Freeze() *FixtureSet {
+	return &FixtureSet{fixture: f.clone()}
+}
+
+// clone はトランザクションを持たない Fixture の複製を作成する
+// レコードは読み込み後に変更されないため共有する
+func (f *Fixture) clone() *Fixture {
+	c := *f
+	c.tx = nil
+	c.insertedTables = nil
+	c.tableOrder = append([]string(nil), f.tableOrder...)
+	c.fixtures = make(map[string][]*record, len(f.fixtures))
+	for tableName, records := range f.fixtures {
+		c.fixtures[tableName] = append([]*record(nil), records...)
+	}
+	c.sources = make(map[string][]string, len(f.sources))
+	for tableName, sources := range f.sources {
+		c.sources[tableName] = append([]string(nil), sources...)
+	}
+	return &c
+}
+
+// Tables は FixtureSet に含まれるテーブル名を読み込み順で返す
+func (s *FixtureSet) Tables() []string {
+	return s.fixture.Tables()
+}
+
+// NewSession は独立したトランザクションを持つ Session を開始する
+func (s *FixtureSet) NewSession(ctx context.Context, opts *sql.TxOptions) (*Session, error) {
+	session := &Session{fixture: s.fixture.clone()}
+	if err := session.fixture.BeginTx(ctx, opts); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Session は FixtureSet から作成された、1つのトランザクションに閉じたフィクスチャの利用単位
+// Session 自体は並行利用に対して安全ではないため、サブテストごとに作成する
+type Session struct {
+	fixture *Fixture
+}
+
+// Tx はセッションのトランザクションを返す
+func (s *Session) Tx() *sql.Tx {
+	return s.fixture.tx
+}
+
+// InsertFixtures はセッションのトランザクション内にフィクスチャデータを挿入する
+func (s *Session) InsertFixtures(ctx context.Context) error {
+	if s.fixture.tx == nil {
+		return fmt.Errorf("transaction not started")
+	}
+	return s.fixture.InsertFixturesContext(ctx)
+}
+
+// Commit はセッションのトランザクションをコミットする
+func (s *Session) Commit() error {
+	return s.fixture.CommitTransaction()
+}
+
+// Rollback はセッションのトランザクションをロールバックする
+func (s *Session) Rollback() error {
+	return s.fixture.RollbackTransaction()
+}
@@ -0,0 +1,171 @@
+package yamlfix
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// snapshotDir はスナップショットを保存するディレクトリ
+const snapshotDir = "testdata/snapshots"
+
+// UpdateSnapshots は Snapshot がスナップショットを比較せずに書き出すかどうか
+// テストパッケージで flag.BoolVar(&yamlfix.UpdateSnapshots, "update", false, "...") のように独自のフラグと結び付ける
+var UpdateSnapshots bool
+
+// updateSnapshotsEnv は UpdateSnapshots の代わりにスナップショットの書き出しを指定する環境変数
+const updateSnapshotsEnv = "YAMLFIX_UPDATE_SNAPSHOTS"
+
+// updateSnapshots はスナップショットを書き出すかを返す（UpdateSnapshots または環境変数で指定）
+func updateSnapshots() bool {
+	if UpdateSnapshots {
+		return true
+	}
+	update, _ := strconv.ParseBool(os.Getenv(updateSnapshotsEnv))
+	return update
+}
+
+// OnlyTables は Snapshot で保存・比較するテーブル、AssertDatabase で検証するテーブルを限定する
+func OnlyTables(tables ...string) AssertOption {
+	return func(o *assertOptions) {
+		o.tables = append(o.tables, tables...)
+	}
+}
+
+// Snapshot はテーブルの内容を testdata/snapshots/<テスト名>/<name>.yaml のスナップショットと比較する
+// UpdateSnapshots を指定した場合はフィクスチャと同じ形式で書き出す
+// スナップショットがない場合も書き出すが、テスト名の変更などに気付けるようテストを失敗させる
+// 対象はフィクスチャを読み込んだテーブル（OnlyTables で指定可能）で、IgnoreColumns のカラムは書き出さない
+func (tf *TestFixture) Snapshot(t testing.TB, name string, opts ...AssertOption) {
+	t.Helper()
+	tf.snapshot(tf.ctx, t, name, opts)
+}
+
+// Snapshot はテーブルの内容をセッションのトランザクションから読み取ってスナップショットと比較する
+func (s *Session) Snapshot(t testing.TB, name string, opts ...AssertOption) {
+	t.Helper()
+	s.fixture.snapshot(t.Context(), t, name, opts)
+}
+
+// snapshot はテーブルの内容をスナップショットと比較し、必要な場合は書き出す
+func (f *Fixture) snapshot(ctx context.Context, t testing.TB, name string, opts []AssertOption) {
+	t.Helper()
+
+	options := newAssertOptions(opts)
+	tables := options.tables
+	if len(tables) == 0 {
+		tables = f.Tables()
+	}
+	if len(tables) == 0 {
+		t.Fatalf("failed to take snapshot %s: no tables to snapshot", name)
+	}
+
+	path := filepath.Join(snapshotDir, filepath.FromSlash(t.Name()), name+".yaml")
+	_, err := os.Stat(path)
+	missing := errors.Is(err, fs.ErrNotExist)
+	if update := updateSnapshots(); update || missing {
+		data, err := f.dumpTables(ctx, tables, options)
+		if err != nil {
+			t.Fatalf("failed to take snapshot %s: %v", name, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to write snapshot %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("failed to write snapshot %s: %v", name, err)
+		}
+		if !update {
+			t.Errorf("snapshot %s did not exist and has been written; review it and run the test again", path)
+			return
+		}
+		t.Logf("wrote snapshot %s", path)
+		return
+	}
+
+	// スナップショットはフィクスチャとして読み込んで比較する（FS の指定によらずOSのファイルシステムから読む）
+	expected := f.newChild()
+	expected.template = false
+	if err := expected.loadFile(osFS{}, path); err != nil {
+		t.Fatalf("failed to load snapshot %s: %v", name, err)
+	}
+
+	matched := true
+	for _, table := range tables {
+		records, ok := expected.fixtures[table]
+		if !ok {
+			t.Errorf("snapshot %s does not contain table %s", path, table)
+			matched = false
+			continue
+		}
+		if !f.assertRecords(ctx, t, table, records, options) {
+			matched = false
+		}
+	}
+	if !matched {
+		t.Logf("set yamlfix.UpdateSnapshots or %s=1 to update snapshot %s", updateSnapshotsEnv, path)
+	}
+}
+
+// dumpTables はテーブルの内容を複数テーブル形式のYAMLに書き出す
+// 行は主キーの順に並べ、カラムはテーブルの定義順に書き出す
+func (f *Fixture) dumpTables(ctx context.Context, tables []string, options *assertOptions) ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, table := range tables {
+		actual, err := f.queryTable(ctx, f.readExecutor(options), table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read table %s: %w", table, err)
+		}
+
+		primaryKey := f.primaryKey(table)
+		if hasColumns(actual.columns, primaryKey) {
+			sortRows(primaryKey, actual.rows)
+		}
+
+		rows := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, row := range actual.rows {
+			node := &yaml.Node{Kind: yaml.MappingNode}
+			for _, col := range actual.columns {
+				if options.ignoreColumns[col] {
+					continue
+				}
+				value := &yaml.Node{}
+				if err := value.Encode(snapshotValue(row[col])); err != nil {
+					return nil, fmt.Errorf("failed to encode column %s of table %s: %w", col, table, err)
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: col}, value)
+			}
+			rows.Content = append(rows.Content, node)
+		}
+
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: table}, rows)
+	}
+
+	// フィクスチャのファイルと同じく2スペースでインデントする
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// snapshotValue はフィクスチャとして読み込み直せる形式に値を変換する
+func snapshotValue(value interface{}) interface{} {
+	if t, ok := value.(time.Time); ok {
+		return t.Format("2006-01-02 15:04:05.999999999Z07:00")
+	}
+	return value
+}
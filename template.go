@@ -0,0 +1,75 @@
+package yamlfix
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+)
+
+// evaluateTemplate はYAMLデータを text/template として評価する
+// テンプレート名にファイル名を使うため、エラーには "ファイル名:行番号" が含まれる
+func (f *Fixture) evaluateTemplate(data []byte, filename string) ([]byte, error) {
+	tmpl, err := template.New(displayName(filename)).
+		Option("missingkey=error").
+		Funcs(templateFuncs(time.Now())).
+		Funcs(f.funcMap).
+		Parse(string(data))
+	if err != nil {
+		return nil, &LoadError{SourceFile: filename, Err: fmt.Errorf("failed to parse template: %w", err)}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return nil, &LoadError{SourceFile: filename, Err: fmt.Errorf("failed to evaluate template: %w", err)}
+	}
+	return buf.Bytes(), nil
+}
+
+// templateFuncs はテンプレートで使用できる組み込み関数を返す
+// now はファイル内で同じ時刻を返すよう評価開始時の時刻に固定する
+func templateFuncs(now time.Time) template.FuncMap {
+	return template.FuncMap{
+		// {{ now | addDate 0 0 -3 | date "2006-01-02 15:04:05" }}
+		"now": func() time.Time { return now },
+		"addDate": func(years, months, days int, t time.Time) time.Time {
+			return t.AddDate(years, months, days)
+		},
+		"date": func(layout string, t time.Time) string { return t.Format(layout) },
+		"uuid": newUUID,
+		"seq":  seq,
+		"env":  os.Getenv,
+	}
+}
+
+// seq は連番のスライスを返す（seq n は 1..n、seq a b は a..b）
+func seq(bounds ...int) ([]int, error) {
+	var first, last int
+	switch len(bounds) {
+	case 1:
+		first, last = 1, bounds[0]
+	case 2:
+		first, last = bounds[0], bounds[1]
+	default:
+		return nil, fmt.Errorf("seq expects 1 or 2 arguments, got %d", len(bounds))
+	}
+
+	values := make([]int, 0, max(last-first+1, 0))
+	for i := first; i <= last; i++ {
+		values = append(values, i)
+	}
+	return values, nil
+}
+
+// newUUID はランダムなUUID（バージョン4）を生成する
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // バージョン4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 バリアント
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -1,36 +1,101 @@
 package yamlfix
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"testing"
 )
 
 // TestFixture はテスト用のフィクスチャヘルパー
 type TestFixture struct {
 	*Fixture
-	t *testing.T
+	t      testing.TB
+	ctx    context.Context
+	layers []fixtureLayer
 }
 
 // NewTestFixture はテスト用の新しいFixtureインスタンスを作成する
-func NewTestFixture(t *testing.T, db *sql.DB) *TestFixture {
-	return &TestFixture{
-		Fixture: New(Config{
-			DB:           db,
-			AutoRollback: true, // テスト時は常に自動ロールバック
-		}),
-		t: t,
+// テスト・ベンチマーク・ファズテストで利用でき、終了時のロールバックは t.Cleanup で自動的に行われる
+func NewTestFixture(t testing.TB, db *sql.DB) *TestFixture {
+	return NewTestFixtureWithConfig(t, Config{DB: db})
+}
+
+// NewTestFixtureWithConfig は設定を指定してテスト用のFixtureインスタンスを作成する
+// AutoRollback は常に有効になる
+func NewTestFixtureWithConfig(t testing.TB, config Config) *TestFixture {
+	config.AutoRollback = true // テスト時は常に自動ロールバック
+
+	tf := &TestFixture{
+		Fixture: New(config),
+		t:       t,
+		ctx:     t.Context(), // テスト終了時にキャンセルされる
+	}
+
+	t.Cleanup(tf.cleanup)
+	return tf
+}
+
+// cleanup はテスト終了時に開いたままのトランザクションをロールバックし、
+// TruncateStrategy の場合はフィクスチャを挿入したテーブルを空にする
+func (tf *TestFixture) cleanup() {
+	if tf.HasTransaction() {
+		tf.t.Errorf("transaction was left open at the end of the test; rolled back")
+
+		// t.Context() のキャンセルにより既にロールバックされている場合がある
+		if err := tf.RollbackTransaction(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tf.t.Errorf("failed to rollback transaction: %v", err)
+		}
+	}
+
+	if tf.strategy == TruncateStrategy {
+		// t.Context() はクリーンアップ時には既にキャンセルされている
+		if err := tf.TruncateFixtures(context.Background()); err != nil {
+			tf.t.Errorf("failed to truncate fixtures: %v", err)
+		}
 	}
 }
 
+// rollback はテスト実行後にトランザクションをロールバックし、テストコードが
+// トランザクションを終了させていた場合はテストを失敗させる
+func (tf *TestFixture) rollback() {
+	tf.t.Helper()
+
+	if !tf.autoRollback || !tf.HasTransaction() {
+		return
+	}
+
+	// トランザクションごとロールバックするためレイヤーも破棄する
+	tf.layers = nil
+
+	err := tf.RollbackTransaction()
+	if errors.Is(err, sql.ErrTxDone) {
+		tf.t.Errorf("transaction was committed or rolled back unexpectedly before the fixture rollback")
+		return
+	}
+	if err != nil {
+		tf.t.Errorf("failed to rollback transaction: %v", err)
+	}
+}
+
+// Context はフィクスチャ操作に使用するコンテキストを返す
+func (tf *TestFixture) Context() context.Context {
+	return tf.ctx
+}
+
+// SetContext はフィクスチャ操作に使用するコンテキストを設定する（デッドラインの指定など）
+func (tf *TestFixture) SetContext(ctx context.Context) {
+	tf.ctx = ctx
+}
+
 // SetupTest はテストのセットアップを行う
+// パスにはファイル・ディレクトリ・グロブ（testdata/users/*.yaml、testdata/**/*.yml など）を指定できる
 func (tf *TestFixture) SetupTest(yamlPaths ...string) {
 	tf.t.Helper()
 
-	// ファイルまたはディレクトリから読み込み
-	for _, path := range yamlPaths {
-		if err := tf.LoadFromFile(path); err != nil {
-			tf.t.Fatalf("failed to load fixtures: %v", err)
-		}
+	// ファイルまたはディレクトリから読み込み（グロブは辞書順に展開する）
+	if err := tf.loadPatterns(tf.fsys, yamlPaths); err != nil {
+		tf.t.Fatalf("failed to load fixtures: %v", err)
 	}
 }
 
@@ -40,20 +105,17 @@ func (tf *TestFixture) RunTest(testFn func(tx *sql.Tx)) {
 }
 
 // RunTestWithSetup はセットアップ（テーブル作成等）を行った後、フィクスチャを挿入してテストを実行する
+// TruncateStrategy の場合はセットアップとフィクスチャをコミットし、testFn には nil を渡す
 func (tf *TestFixture) RunTestWithSetup(setupFn func(tx *sql.Tx), testFn func(tx *sql.Tx)) {
 	tf.t.Helper()
 
 	// トランザクション開始
-	err := tf.BeginTransaction()
+	err := tf.BeginTx(tf.ctx, nil)
 	if err != nil {
 		tf.t.Fatalf("failed to start transaction: %v", err)
 	}
 
-	defer func() {
-		if tf.autoRollback {
-			tf.RollbackTransaction()
-		}
-	}()
+	defer tf.rollback()
 
 	// セットアップ段階（テーブル作成等）
 	if setupFn != nil {
@@ -61,40 +123,99 @@ func (tf *TestFixture) RunTestWithSetup(setupFn func(tx *sql.Tx), testFn func(tx
 	}
 
 	// フィクスチャデータを自動挿入
-	err = tf.InsertFixtures()
+	err = tf.InsertFixturesContext(tf.ctx)
 	if err != nil {
 		tf.t.Fatalf("failed to insert fixtures: %v", err)
 	}
 
+	// テスト対象のコードが別の接続から参照できるようコミットする（後片付けは cleanup で行う）
+	if tf.strategy == TruncateStrategy {
+		if err := tf.CommitTransaction(); err != nil {
+			tf.t.Fatalf("failed to commit fixtures: %v", err)
+		}
+		testFn(nil)
+		return
+	}
+
 	// テストコードを実行
 	testFn(tf.tx)
 }
 
 // RunTestWithCustomSetup は手動でフィクスチャ挿入タイミングを制御したい場合に使用する
+// TruncateStrategy の場合はトランザクションを開始せず、testFn には nil を渡す
 func (tf *TestFixture) RunTestWithCustomSetup(testFn func(tx *sql.Tx)) {
 	tf.t.Helper()
 
+	// InsertTestData はトランザクションがなければデータベースに直接挿入する
+	if tf.strategy == TruncateStrategy {
+		testFn(nil)
+		return
+	}
+
 	// トランザクション開始
-	err := tf.BeginTransaction()
+	err := tf.BeginTx(tf.ctx, nil)
 	if err != nil {
 		tf.t.Fatalf("failed to start transaction: %v", err)
 	}
 
-	defer func() {
-		if tf.autoRollback {
-			tf.RollbackTransaction()
-		}
-	}()
+	defer tf.rollback()
 
 	// フィクスチャデータは自動挿入しない（手動制御）
 	testFn(tf.tx)
 }
 
+// Run は並列実行されるサブテストを独立したトランザクションで実行する（フィクスチャ自動挿入）
+func (tf *TestFixture) Run(t *testing.T, name string, testFn func(t *testing.T, tx *sql.Tx)) bool {
+	t.Helper()
+	return tf.RunWithSetup(t, name, nil, testFn)
+}
+
+// RunWithSetup はサブテストを t.Parallel() で並列実行し、サブテストごとのトランザクション内で
+// セットアップ・フィクスチャ挿入・テストを行う
+// 呼び出し時点で読み込まれているフィクスチャが使われ、Config.Strategy によらずサブテスト終了時にロールバックされる
+func (tf *TestFixture) RunWithSetup(t *testing.T, name string, setupFn, testFn func(t *testing.T, tx *sql.Tx)) bool {
+	t.Helper()
+
+	// サブテストは並列に実行されるため、読み込み済みのフィクスチャを固定して共有する
+	set := tf.Freeze()
+
+	return t.Run(name, func(t *testing.T) {
+		t.Parallel()
+
+		session, err := set.NewSession(t.Context(), nil)
+		if err != nil {
+			t.Fatalf("failed to start transaction: %v", err)
+		}
+
+		defer func() {
+			err := session.Rollback()
+			if errors.Is(err, sql.ErrTxDone) {
+				t.Errorf("transaction was committed or rolled back unexpectedly before the fixture rollback")
+			} else if err != nil {
+				t.Errorf("failed to rollback transaction: %v", err)
+			}
+		}()
+
+		// セットアップ段階（テーブル作成等）
+		if setupFn != nil {
+			setupFn(t, session.Tx())
+		}
+
+		// フィクスチャデータを自動挿入
+		if err := session.InsertFixtures(t.Context()); err != nil {
+			t.Fatalf("failed to insert fixtures: %v", err)
+		}
+
+		// テストコードを実行
+		testFn(t, session.Tx())
+	})
+}
+
 // InsertTestData はテスト内でフィクスチャデータを挿入する
 func (tf *TestFixture) InsertTestData() {
 	tf.t.Helper()
 
-	err := tf.InsertFixtures()
+	err := tf.InsertFixturesContext(tf.ctx)
 	if err != nil {
 		tf.t.Fatalf("failed to insert fixtures: %v", err)
 	}
@@ -111,6 +232,8 @@ func (tf *TestFixture) GetTransaction() *sql.Tx {
 }
 
 // TearDownTest はテストのクリーンアップを行う
+// RunTest などはトランザクションを自ら終了するため不要だが、BeginTransaction で開始したトランザクションは
+// TearDownTest で終了しないとテスト終了時に開いたままとしてテストを失敗させる
 func (tf *TestFixture) TearDownTest() {
 	tf.t.Helper()
 
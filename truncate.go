@@ -0,0 +1,148 @@
+package yamlfix
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Strategy はテストで挿入したフィクスチャの後片付けの方法を表す
+type Strategy int
+
+const (
+	// TransactionStrategy はフィクスチャをトランザクション内で挿入し、テスト終了時にロールバックする
+	TransactionStrategy Strategy = iota
+	// TruncateStrategy はフィクスチャをコミットし、テスト終了時に挿入したテーブルを空にして自動採番をリセットする
+	// テスト対象のコードが独自の接続を使う場合や内部でコミットする場合に使う
+	TruncateStrategy
+)
+
+// Truncater はテーブルを空にして自動採番をリセットする方法が方言ごとに異なる場合に実装するインターフェース
+// 実装していない方言では参照元のテーブルから順に DELETE する
+type Truncater interface {
+	// TruncateTables は tables のすべての行を削除し、自動採番をリセットする
+	// tables は参照元のテーブルが先に来る順に並んでいる
+	TruncateTables(ctx context.Context, executor Executor, tables []string) error
+}
+
+// TruncateTables は TRUNCATE ... RESTART IDENTITY CASCADE でテーブルとシーケンスをまとめてリセットする
+func (d PostgreSQLDialect) TruncateTables(ctx context.Context, executor Executor, tables []string) error {
+	_, err := executor.ExecContext(ctx, "TRUNCATE TABLE "+quoteColumns(d, tables)+" RESTART IDENTITY CASCADE")
+	return err
+}
+
+// TruncateTables は外部キー検査を無効化して TRUNCATE する（AUTO_INCREMENT もリセットされる）
+// FOREIGN_KEY_CHECKS はセッション単位のため executor は1つの接続である必要がある
+func (d MySQLDialect) TruncateTables(ctx context.Context, executor Executor, tables []string) (err error) {
+	restore, err := execWithRestore(ctx, executor, "SET FOREIGN_KEY_CHECKS = 0", "SET FOREIGN_KEY_CHECKS = 1")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if restoreErr := restore(); restoreErr != nil && err == nil {
+			err = restoreErr
+		}
+	}()
+
+	for _, table := range tables {
+		if _, err := executor.ExecContext(ctx, "TRUNCATE TABLE "+d.QuoteIdentifier(table)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TruncateTables は DELETE した後、AUTOINCREMENT のカウンタを sqlite_sequence から削除する
+func (d SQLiteDialect) TruncateTables(ctx context.Context, executor Executor, tables []string) error {
+	if err := deleteTables(ctx, executor, d, tables); err != nil {
+		return err
+	}
+
+	// sqlite_sequence は AUTOINCREMENT のテーブルを作成するまで存在しない
+	exists, err := queryStrings(ctx, executor, `SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'sqlite_sequence'`)
+	if err != nil || len(exists) == 0 {
+		return err
+	}
+
+	args := make([]interface{}, len(tables))
+	placeholders := make([]string, len(tables))
+	for i, table := range tables {
+		args[i] = table
+		placeholders[i] = "?"
+	}
+	_, err = executor.ExecContext(ctx, "DELETE FROM sqlite_sequence WHERE name IN ("+strings.Join(placeholders, ", ")+")", args...)
+	return err
+}
+
+// TruncateTables は DELETE した後、IDENTITY カラムを持つテーブルの採番を DBCC CHECKIDENT でリセットする
+func (d SQLServerDialect) TruncateTables(ctx context.Context, executor Executor, tables []string) error {
+	if err := deleteTables(ctx, executor, d, tables); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		name := d.tableLiteral(table)
+		query := fmt.Sprintf("IF OBJECTPROPERTY(OBJECT_ID(%s), 'TableHasIdentity') = 1 DBCC CHECKIDENT (%s, RESEED, 0)", name, name)
+		if _, err := executor.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteTables は tables の順にすべての行を DELETE する
+func deleteTables(ctx context.Context, executor Executor, d Dialect, tables []string) error {
+	for _, table := range tables {
+		if _, err := executor.ExecContext(ctx, "DELETE FROM "+d.QuoteIdentifier(table)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TruncateFixtures はフィクスチャを挿入したテーブルを参照元から順に空にし、自動採番をリセットする
+// TruncateStrategy ではテスト終了時に自動的に呼ばれる
+func (f *Fixture) TruncateFixtures(ctx context.Context) error {
+	if len(f.insertedTables) == 0 {
+		return nil
+	}
+
+	// 挿入順の逆に削除すれば参照元が先に削除される
+	tables := make([]string, len(f.insertedTables))
+	for i, tableName := range f.insertedTables {
+		tables[len(tables)-1-i] = tableName
+	}
+
+	var executor Executor
+	if f.tx != nil {
+		executor = f.tx
+	} else {
+		// セッション変数を使う方言があるため1つの接続で実行する
+		conn, err := f.db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get connection: %w", err)
+		}
+		defer conn.Close()
+		executor = conn
+	}
+
+	var err error
+	if truncater, ok := f.dialect.(Truncater); ok {
+		err = truncater.TruncateTables(ctx, executor, tables)
+	} else {
+		err = deleteTables(ctx, executor, f.dialect, tables)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to truncate tables %s: %w", strings.Join(tables, ", "), err)
+	}
+
+	f.insertedTables = nil
+	return nil
+}
+
+// markInserted はフィクスチャを挿入したテーブルを挿入順に記録する
+func (f *Fixture) markInserted(tableName string) {
+	if !containsString(f.insertedTables, tableName) {
+		f.insertedTables = append(f.insertedTables, tableName)
+	}
+}
@@ -0,0 +1,310 @@
+package yamlfix
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnInfo はスキーマから取得したカラムの定義
+type ColumnInfo struct {
+	Name       string // カラム名
+	Type       string // データベース上の型名
+	Nullable   bool   // NULL を許可するかどうか
+	HasDefault bool   // デフォルト値・自動採番・生成列のいずれかにより値を省略できるかどうか
+}
+
+// ColumnIntrospector はテーブルのカラム定義をスキーマから取得できる方言が実装するインターフェース
+type ColumnIntrospector interface {
+	// Columns は table のカラム定義を返す（テーブルが存在しない場合は空）
+	Columns(ctx context.Context, executor Executor, table string) ([]ColumnInfo, error)
+}
+
+// Columns は pragma_table_info からカラム定義を取得する（INTEGER PRIMARY KEY は自動採番とみなす）
+func (SQLiteDialect) Columns(ctx context.Context, executor Executor, table string) ([]ColumnInfo, error) {
+	return queryColumns(ctx, executor, `
+		SELECT name, type, "notnull" = 0, dflt_value IS NOT NULL OR (pk = 1 AND upper(type) = 'INTEGER')
+		FROM pragma_table_info(?)
+		ORDER BY cid`, table)
+}
+
+// Columns は information_schema からカラム定義を取得する
+func (MySQLDialect) Columns(ctx context.Context, executor Executor, table string) ([]ColumnInfo, error) {
+	return queryColumns(ctx, executor, `
+		SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE = 'YES', COLUMN_DEFAULT IS NOT NULL OR EXTRA <> ''
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE()
+		  AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION`, table)
+}
+
+// Columns は information_schema からカラム定義を取得する
+func (PostgreSQLDialect) Columns(ctx context.Context, executor Executor, table string) ([]ColumnInfo, error) {
+	return queryColumns(ctx, executor, `
+		SELECT column_name, data_type, is_nullable = 'YES',
+		       column_default IS NOT NULL OR is_identity = 'YES' OR is_generated <> 'NEVER'
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()
+		  AND table_name = $1
+		ORDER BY ordinal_position`, table)
+}
+
+// Columns は sys.columns からカラム定義を取得する
+func (SQLServerDialect) Columns(ctx context.Context, executor Executor, table string) ([]ColumnInfo, error) {
+	return queryColumns(ctx, executor, `
+		SELECT c.name, t.name, c.is_nullable,
+		       CAST(CASE WHEN c.default_object_id <> 0 OR c.is_identity = 1 OR c.is_computed = 1 THEN 1 ELSE 0 END AS bit)
+		FROM sys.columns c
+		JOIN sys.types t ON t.user_type_id = c.user_type_id
+		WHERE c.object_id = OBJECT_ID(@p1)
+		ORDER BY c.column_id`, table)
+}
+
+// Columns は user_tab_columns からカラム定義を取得する
+func (OracleDialect) Columns(ctx context.Context, executor Executor, table string) ([]ColumnInfo, error) {
+	return queryColumns(ctx, executor, `
+		SELECT column_name, data_type,
+		       CASE WHEN nullable = 'Y' THEN 1 ELSE 0 END,
+		       CASE WHEN default_length > 0 OR identity_column = 'YES' THEN 1 ELSE 0 END
+		FROM user_tab_columns
+		WHERE table_name = UPPER(:1)
+		ORDER BY column_id`, table)
+}
+
+// ValidationError はスキーマとフィクスチャの不整合の一覧
+type ValidationError struct {
+	Problems []string // "ファイル名:行番号: 内容" 形式の問題
+}
+
+// Error はエラーメッセージを返す
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("fixture validation failed with %d problem(s):\n  %s", len(e.Problems), strings.Join(e.Problems, "\n  "))
+}
+
+// Validate はスキーマを参照してフィクスチャを挿入前に検証する
+// 存在しないテーブルやカラム、値のない NOT NULL カラム、明らかな型の不一致を *ValidationError で返す
+func (f *Fixture) Validate(ctx context.Context) error {
+	introspector, ok := f.dialect.(ColumnIntrospector)
+	if !ok {
+		return fmt.Errorf("dialect %s does not support column introspection", f.dialect.Name())
+	}
+
+	executor := f.getExecutor()
+	var problems []string
+	for _, tableName := range f.tableOrder {
+		records := f.fixtures[tableName]
+		if len(records) == 0 {
+			continue
+		}
+
+		columns, err := introspector.Columns(ctx, executor, tableName)
+		if err != nil {
+			return fmt.Errorf("failed to read columns of table %s: %w", tableName, err)
+		}
+		if len(columns) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: unknown table %s", records[0].location(), tableName))
+			continue
+		}
+
+		problems = append(problems, f.validateTable(tableName, records, columns)...)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateTable はテーブルのレコードをカラム定義と照合する
+func (f *Fixture) validateTable(tableName string, records []*record, columns []ColumnInfo) []string {
+	// カラム名は大文字小文字を区別せずに照合する
+	schema := make(map[string]ColumnInfo, len(columns))
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		schema[strings.ToLower(col.Name)] = col
+		names[i] = col.Name
+	}
+
+	// NullMissingColumns ではテーブル内のいずれかのレコードにあるカラムが全レコードに NULL で挿入される
+	var union *record
+	if f.missingColumns == NullMissingColumns {
+		union = &record{columns: unionColumns(records)}
+	}
+
+	var problems []string
+	for _, rec := range records {
+		for _, col := range rec.columns {
+			info, ok := schema[strings.ToLower(col)]
+			if !ok {
+				problem := fmt.Sprintf("%s: unknown column %s in table %s", rec.location(), col, tableName)
+				if suggestion := closestName(col, names); suggestion != "" {
+					problem += fmt.Sprintf(" (did you mean %s?)", suggestion)
+				}
+				problems = append(problems, problem)
+				continue
+			}
+
+			value := rec.values[col]
+			if value == nil && !info.Nullable {
+				problems = append(problems, fmt.Sprintf("%s: column %s in table %s is NOT NULL but the value is null", rec.location(), col, tableName))
+				continue
+			}
+			if !compatibleValue(info.Type, value) {
+				problems = append(problems, fmt.Sprintf("%s: value %v of column %s does not match type %s in table %s", rec.location(), value, col, info.Type, tableName))
+			}
+		}
+
+		// 省略すると NULL になる NOT NULL カラムを検出する
+		// デフォルト値はINSERTからカラムを省いた場合のみ適用される
+		for _, info := range columns {
+			if info.Nullable {
+				continue
+			}
+			if _, ok := rec.values[info.Name]; ok || hasColumnFold(rec, info.Name) {
+				continue
+			}
+			if union != nil && hasColumnFold(union, info.Name) {
+				problems = append(problems, fmt.Sprintf("%s: missing value for NOT NULL column %s in table %s is inserted as null by NullMissingColumns", rec.location(), info.Name, tableName))
+				continue
+			}
+			if info.HasDefault {
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("%s: missing value for NOT NULL column %s in table %s", rec.location(), info.Name, tableName))
+		}
+	}
+
+	return problems
+}
+
+// hasColumnFold はレコードが大文字小文字を区別せずにカラムを持つかどうかを判定する
+func hasColumnFold(rec *record, column string) bool {
+	for _, col := range rec.columns {
+		if strings.EqualFold(col, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// compatibleValue は値がカラムの型に明らかに合わない場合に false を返す
+// 判定できない型や生のSQL式・参照は常に true を返す
+func compatibleValue(columnType string, value interface{}) bool {
+	switch value.(type) {
+	case nil, rawSQL, reference, []byte:
+		return true
+	}
+
+	switch typeCategory(columnType) {
+	case "numeric":
+		switch v := value.(type) {
+		case int, int64, uint64, float64, bool:
+			return true
+		case string:
+			_, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			return err == nil
+		}
+		return false
+	case "boolean":
+		switch v := value.(type) {
+		case bool, int:
+			return true
+		case string:
+			_, err := strconv.ParseBool(strings.TrimSpace(v))
+			return err == nil
+		}
+		return false
+	case "temporal":
+		switch v := value.(type) {
+		case time.Time:
+			return true
+		case string:
+			s := strings.TrimSpace(v)
+			if _, ok := parseTime(s, time.UTC); ok {
+				return true
+			}
+			_, err := time.Parse("15:04:05.999999999", s)
+			return err == nil
+		}
+		return false
+	}
+	return true
+}
+
+// typeCategory はデータベースの型名を数値・真偽値・日時に分類する（分類できない場合は空文字）
+func typeCategory(columnType string) string {
+	name := strings.ToLower(columnType)
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = name[:i]
+	}
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	switch fields[0] {
+	case "int", "integer", "smallint", "bigint", "tinyint", "mediumint", "int2", "int4", "int8",
+		"serial", "bigserial", "smallserial", "decimal", "numeric", "number", "real", "float", "float4", "float8", "double":
+		return "numeric"
+	case "bool", "boolean", "bit":
+		return "boolean"
+	case "date", "datetime", "datetime2", "smalldatetime", "datetimeoffset", "timestamp", "timestamptz", "time", "timetz":
+		return "temporal"
+	}
+	return ""
+}
+
+// closestName は name に最も近い候補を返す（十分に近い候補がない場合は空文字）
+func closestName(name string, candidates []string) string {
+	best, bestDistance := "", len(name)/3+2
+	for _, candidate := range candidates {
+		if d := levenshtein(strings.ToLower(name), strings.ToLower(candidate)); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// levenshtein は2つの文字列の編集距離を返す
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// queryColumns は名前・型・NULL許可・デフォルト有無の4カラムを返すクエリを実行する
+func queryColumns(ctx context.Context, executor Executor, query string, args ...interface{}) ([]ColumnInfo, error) {
+	rows, err := executor.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	for rows.Next() {
+		var col ColumnInfo
+		if err := rows.Scan(&col.Name, &col.Type, &col.Nullable, &col.HasDefault); err != nil {
+			return nil, err
+		}
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}